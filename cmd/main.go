@@ -2,194 +2,361 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	authorizationv1 "k8s.io/api/authorization/v1"
-	core "k8s.io/api/core/v1"
-	authorizationv1Client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
-	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 
-	dummysignup "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/dummy"
+	"github.com/konflux-ci/workspace-manager/pkg/accesscache"
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/changes"
+	"github.com/konflux-ci/workspace-manager/pkg/handlers/signup"
+	_ "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/dummy"
+	_ "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+	"github.com/konflux-ci/workspace-manager/pkg/handlers/workspaces"
+	"github.com/konflux-ci/workspace-manager/pkg/notify"
+	"github.com/konflux-ci/workspace-manager/pkg/policy"
+	"github.com/konflux-ci/workspace-manager/pkg/rbac"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	"github.com/konflux-ci/workspace-manager/pkg/username"
 )
 
 var (
 	scheme = runtime.NewScheme()
 )
 
-func init() {
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+// Signup backend used when the SIGNUP_BACKEND environment variable is not set
+const defaultSignupBackend = "namespace"
+
+// Namespace holding the signup state when the SIGNUP_NAMESPACE environment
+// variable is not set
+const defaultSignupNamespace = "workspace-manager"
+
+// API group and resource on which administrators need permissions to manage signups
+const (
+	signupAPIGroup = "workspaces.konflux.ci"
+	signupResource = "usersignups"
+)
+
+// Read an environment variable, falling back to def when it is not set
+func getEnv(name string, def string) string {
+	if value, found := os.LookupEnv(name); found {
+		return value
+	}
+	return def
 }
 
-type NamespaceWithAccess func(e *echo.Echo, c echo.Context, authCl authorizationv1Client.AuthorizationV1Interface, allNamespaces []core.Namespace) ([]core.Namespace, error)
+// Read a comma separated list from an environment variable
+func getEnvList(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
 
-// Given all relevant user namespaces, return all workspaces for the calling user
-func getWorkspacesWithAccess(
-	e *echo.Echo, c echo.Context, allNamespaces []core.Namespace, getNamespacesWithAccess NamespaceWithAccess,
-) (crt.WorkspaceList, error) {
-	cfg, err := config.GetConfig()
+// Load the access policy from the file given in the ACCESS_POLICY_FILE
+// environment variable and keep it up to date. Everyone is allowed when
+// no file is given.
+func accessPolicy(e *echo.Echo) (policy.Evaluator, error) {
+	path := os.Getenv("ACCESS_POLICY_FILE")
+	if path == "" {
+		return policy.AllowAll{}, nil
+	}
+	interval, err := time.ParseDuration(getEnv("ACCESS_POLICY_RELOAD_INTERVAL", "30s"))
 	if err != nil {
-		e.Logger.Fatal(err)
+		return nil, err
 	}
-	clientset, err := kubernetes.NewForConfig(cfg)
+	loader, err := policy.Load(path)
 	if err != nil {
-		e.Logger.Fatal(err)
+		return nil, err
 	}
+	go loader.Watch(context.Background(), interval, func(err error) {
+		e.Logger.Errorf("failed to reload the access policy, keeping the previous one: %v", err)
+	})
+	return loader, nil
+}
 
-	authCl := clientset.AuthorizationV1()
-	namespaces, err := getNamespacesWithAccess(e, c, authCl, allNamespaces)
+// Read the signup backend options from the environment, the users are bound
+// to ownerRole in the namespaces provisioned for them with the default tier
+func signupOptions(
+	cl client.Client,
+	accessPolicy policy.Evaluator,
+	cluster signup.ClusterInfo,
+	catalog *tiers.Catalog,
+	ownerRole string,
+) (signup.Options, error) {
+	approvalRequired, err := strconv.ParseBool(getEnv("SIGNUP_APPROVAL_REQUIRED", "false"))
 	if err != nil {
-		e.Logger.Fatal(err)
+		return signup.Options{}, err
 	}
+	gracePeriod, err := time.ParseDuration(getEnv("DEACTIVATION_GRACE_PERIOD", "720h"))
+	if err != nil {
+		return signup.Options{}, err
+	}
+	verificationRequired, err := strconv.ParseBool(getEnv("SIGNUP_VERIFICATION_REQUIRED", "false"))
+	if err != nil {
+		return signup.Options{}, err
+	}
+	verification, err := verificationOptions()
+	if err != nil {
+		return signup.Options{}, err
+	}
+	var notifier notify.Notifier
+	if verificationRequired {
+		if notifier, err = verificationNotifier(); err != nil {
+			return signup.Options{}, err
+		}
+	}
+	return signup.Options{
+		Client:                  cl,
+		Namespace:               getEnv("SIGNUP_NAMESPACE", defaultSignupNamespace),
+		ApprovalRequired:        approvalRequired,
+		AutoApprovedDomains:     getEnvList("SIGNUP_AUTO_APPROVE_DOMAINS"),
+		Policy:                  accessPolicy,
+		Usernames:               username.NewGenerator(getEnvList("RESERVED_USERNAMES")),
+		Tiers:                   catalog,
+		OwnerRole:               ownerRole,
+		DeactivationGracePeriod: gracePeriod,
+		VerificationRequired:    verificationRequired,
+		Notifier:                notifier,
+		Verification:            verification,
+		Cluster:                 cluster,
+	}, nil
+}
 
-	gv := crt.GroupVersion.String()
-
-	var wss []crt.Workspace
-
-	for _, ns := range namespaces {
-		ws := crt.Workspace{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Workspace",
-				APIVersion: gv,
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name: ns.Name,
-			},
-			Status: crt.WorkspaceStatus{
-				Namespaces: []crt.SpaceNamespace{
-					{
-						Name: ns.Name,
-						Type: "default",
-					},
-				},
-				// Owner: "user1",
-				// Role:  "admin",
-			},
+// Gather the URLs of the cluster reported to the signed up users. The
+// environment takes precedence over the URLs found from the cluster itself.
+func clusterInfo(ctx context.Context, e *echo.Echo, cfg *rest.Config, cl client.Client) signup.ClusterInfo {
+	cluster := signup.ClusterInfo{
+		ConsoleURL:      os.Getenv("CONSOLE_URL"),
+		APIEndpoint:     getEnv("API_ENDPOINT", cfg.Host),
+		CheDashboardURL: os.Getenv("CHE_DASHBOARD_URL"),
+	}
+	if cluster.ConsoleURL == "" {
+		consoleURL, err := discoverConsoleURL(ctx, cl)
+		if err != nil {
+			e.Logger.Warnf("failed to discover the console URL: %v", err)
 		}
-		wss = append(wss, ws)
-	}
-
-	workspaces := crt.WorkspaceList{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "WorkspaceList",
-			APIVersion: gv,
-		},
-		Items: wss,
-	}
-	return workspaces, nil
-}
-
-// Get all the namespace in which the calling user is allowed to perform enough actions
-// to allow workspace access
-var getNamespacesWithAccess = func(
-	e *echo.Echo,
-	c echo.Context,
-	authCl authorizationv1Client.AuthorizationV1Interface,
-	allNamespaces []core.Namespace,
-) ([]core.Namespace, error) {
-	var allowedNs []core.Namespace
-	for _, ns := range allNamespaces {
-		notAllowed := false
-		for _, verb := range []string{"list", "watch"} {
-			for _, resource := range []string{"applications", "components"} {
-				allowed, err := runAccessCheck(
-					authCl,
-					c.Request().Header["X-Email"][0],
-					ns.Name,
-					"appstudio.redhat.com",
-					resource,
-					verb,
-				)
-				if err != nil || !allowed {
-					e.Logger.Error(err)
-					notAllowed = true
-					break
-				}
-			}
-			if notAllowed {
-				break // skip next verbs
+		cluster.ConsoleURL = consoleURL
+	}
+	return cluster
+}
+
+// Read the URL of the OpenShift web console from the cluster configuration.
+// An empty URL is returned on clusters other than OpenShift.
+func discoverConsoleURL(ctx context.Context, cl client.Client) (string, error) {
+	console := &unstructured.Unstructured{}
+	console.SetGroupVersionKind(schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Console"})
+	err := cl.Get(ctx, types.NamespacedName{Name: "cluster"}, console)
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	consoleURL, _, err := unstructured.NestedString(console.Object, "status", "consoleURL")
+	return consoleURL, err
+}
+
+// Read the expiry and throttling of the verification codes from the environment
+func verificationOptions() (signup.VerificationOptions, error) {
+	codeExpiry, err := time.ParseDuration(getEnv("VERIFICATION_CODE_EXPIRY", "10m"))
+	if err != nil {
+		return signup.VerificationOptions{}, err
+	}
+	resendInterval, err := time.ParseDuration(getEnv("VERIFICATION_RESEND_INTERVAL", "1m"))
+	if err != nil {
+		return signup.VerificationOptions{}, err
+	}
+	maxAttempts, err := strconv.Atoi(getEnv("VERIFICATION_MAX_ATTEMPTS", "3"))
+	if err != nil {
+		return signup.VerificationOptions{}, err
+	}
+	dailyLimit, err := strconv.Atoi(getEnv("VERIFICATION_DAILY_LIMIT", "5"))
+	if err != nil {
+		return signup.VerificationOptions{}, err
+	}
+	return signup.VerificationOptions{
+		CodeExpiry:     codeExpiry,
+		MaxAttempts:    maxAttempts,
+		ResendInterval: resendInterval,
+		DailyLimit:     dailyLimit,
+	}, nil
+}
+
+// Build the notifier delivering the verification codes selected by the
+// VERIFICATION_NOTIFIER environment variable
+func verificationNotifier() (notify.Notifier, error) {
+	switch kind := getEnv("VERIFICATION_NOTIFIER", "smtp"); kind {
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		from := os.Getenv("SMTP_FROM")
+		if addr == "" || from == "" {
+			return nil, errors.New("SMTP_ADDR and SMTP_FROM are required to send verification codes")
+		}
+		notifier := &notify.SMTP{Addr: addr, From: from}
+		if user := os.Getenv("SMTP_USERNAME"); user != "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
 			}
+			notifier.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
 		}
-		if notAllowed {
-			continue // move to next ns
+		return notifier, nil
+	case "outbox":
+		path := os.Getenv("VERIFICATION_OUTBOX_FILE")
+		if path == "" {
+			return nil, errors.New("VERIFICATION_OUTBOX_FILE is required by the outbox notifier")
 		}
-		allowedNs = append(allowedNs, ns)
+		return &notify.Outbox{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown verification notifier %q, available notifiers: smtp, outbox", kind)
 	}
-	return allowedNs, nil
 }
 
-// Gets all user namespaces that satisfy the provided requirement
-func getUserNamespaces(e *echo.Echo, nameReq labels.Requirement) ([]core.Namespace, error) {
-	cfg, err := config.GetConfig()
-	if err != nil {
-		e.Logger.Fatal(err)
+// Permission administrators need for performing verb on the signups
+func signupAdminPermission(signupNamespace string, verb string) authorizationv1.ResourceAttributes {
+	return authorizationv1.ResourceAttributes{
+		Namespace: signupNamespace,
+		Verb:      verb,
+		Group:     signupAPIGroup,
+		Resource:  signupResource,
 	}
+}
 
-	cl, err := client.New(cfg, client.Options{Scheme: scheme})
+// Permission administrators need for changing the tier of the workspaces,
+// which updates their namespaces
+var tierAdminPermission = authorizationv1.ResourceAttributes{Verb: "update", Resource: "namespaces"}
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+}
+
+// Number of workspace changes kept for the watches to resume from
+const workspaceChangesHistory = 1000
+
+// Load the workspace access rules from the file given in the ACCESS_RULES_FILE
+// environment variable, the default rules are used when no file is given
+func loadAccessRules() (*accessrules.RuleSet, error) {
+	path := os.Getenv("ACCESS_RULES_FILE")
+	if path == "" {
+		return accessrules.Default(), nil
+	}
+	return accessrules.Load(path)
+}
+
+// Read the roles from the file given by the ROLES_FILE environment variable,
+// the default roles are used when it is not set. The admin role, required for
+// managing the workspaces, has to be defined.
+func loadRoles() (roles.Catalog, error) {
+	catalog := roles.Default()
+	if path := os.Getenv("ROLES_FILE"); path != "" {
+		var err error
+		if catalog, err = roles.Load(path); err != nil {
+			return nil, err
+		}
+	}
+	if !catalog.Has(roles.Admin) {
+		return nil, fmt.Errorf("the %s role is not defined", roles.Admin)
+	}
+	return catalog, nil
+}
+
+// Read the tiers from the file given by the TIERS_FILE environment variable,
+// the base tier without templates is used when it is not set
+func loadTiers() (*tiers.Catalog, error) {
+	path := os.Getenv("TIERS_FILE")
+	if path == "" {
+		return tiers.Default(), nil
+	}
+	return tiers.Load(path)
+}
+
+// Read the settings of the access decision cache from the environment
+func accessCacheOptions() (accesscache.Options, error) {
+	ttl, err := time.ParseDuration(getEnv("ACCESS_CACHE_TTL", "30s"))
 	if err != nil {
-		e.Logger.Fatal(err)
+		return accesscache.Options{}, err
 	}
-	req, _ := labels.NewRequirement("konflux.ci/type", selection.In, []string{"user"})
-	selector := labels.NewSelector().Add(*req)
-	selector = selector.Add(nameReq)
-	namespaceList := &core.NamespaceList{}
-	err = cl.List(
-		context.Background(),
-		namespaceList,
-		&client.ListOptions{LabelSelector: selector},
-	)
+	negativeTTL, err := time.ParseDuration(getEnv("ACCESS_CACHE_NEGATIVE_TTL", "5s"))
 	if err != nil {
-		return nil, err
+		return accesscache.Options{}, err
 	}
-	return namespaceList.Items, nil
-}
-
-// check if a user can perform a specific verb on a specific resource in namespace
-func runAccessCheck(
-	authCl authorizationv1Client.AuthorizationV1Interface,
-	user string,
-	namespace string,
-	resourceGroup string,
-	resource string,
-	verb string,
-) (bool, error) {
-	sar := &authorizationv1.LocalSubjectAccessReview{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-		},
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			User: user,
-			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Namespace: namespace,
-				Verb:      verb,
-				Group:     resourceGroup,
-				Resource:  resource,
-			},
-		},
-	}
-	response, err := authCl.LocalSubjectAccessReviews(namespace).Create(
-		context.TODO(), sar, metav1.CreateOptions{},
-	)
+	maxSize, err := strconv.Atoi(getEnv("ACCESS_CACHE_MAX_SIZE", "10000"))
 	if err != nil {
-		return false, err
+		return accesscache.Options{}, err
 	}
-	if response.Status.Allowed {
-		return true, nil
+	return accesscache.Options{
+		TTL:              ttl,
+		NegativeTTL:      negativeTTL,
+		MaxSize:          maxSize,
+		PropagationDelay: workspaces.RBACPropagationDelay,
+	}, nil
+}
+
+// Select how the access of the users to the namespaces is checked from the
+// ACCESS_CHECK_MODE environment variable, either with SubjectAccessReviews
+// or by evaluating the RBAC rules locally, and set it in opts. The RBAC
+// objects are watched through the informers of factory.
+func accessCheckOptions(ctx context.Context, factory informers.SharedInformerFactory, opts *workspaces.Options) error {
+	switch mode := getEnv("ACCESS_CHECK_MODE", "sar"); mode {
+	case "sar":
+		cacheOpts, err := accessCacheOptions()
+		if err != nil {
+			return err
+		}
+		if cacheOpts.TTL > 0 {
+			if opts.AccessDecisions, err = workspaces.NewAccessDecisionCache(ctx, factory, cacheOpts); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "rbac":
+		authorizer, err := rbac.NewAuthorizer(factory)
+		if err != nil {
+			return err
+		}
+		factory.Start(ctx.Done())
+		if !authorizer.WaitForCacheSync(ctx) {
+			return errors.New("failed to sync the RBAC caches")
+		}
+		opts.Authorizer = authorizer
+		return nil
+	default:
+		return fmt.Errorf("unknown access check mode %q, available modes: sar, rbac", mode)
 	}
-	return false, nil
 }
 
 func main() {
@@ -201,49 +368,165 @@ func main() {
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
-	e.POST("/api/v1/signup", dummysignup.DummySignupPostHandler)
+	cfg, err := config.GetConfig()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	cl, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
 
-	e.GET("/api/v1/signup", dummysignup.DummySignupGetHandler)
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	authCl := clientset.AuthorizationV1()
 
-	e.GET("/workspaces", func(c echo.Context) error {
-		nameReq, _ := labels.NewRequirement(
-			"kubernetes.io/metadata.name", selection.Exists, []string{},
-		)
-		userNamespaces, err := getUserNamespaces(e, *nameReq)
-		if err != nil {
-			e.Logger.Fatal(err)
-		}
-		workspaces, err := getWorkspacesWithAccess(e, c, userNamespaces, getNamespacesWithAccess)
-		if err != nil {
-			e.Logger.Fatal(err)
-		}
+	accessCheckWorkers, err := strconv.Atoi(
+		getEnv("ACCESS_CHECK_WORKERS", strconv.Itoa(workspaces.DefaultAccessCheckWorkers)),
+	)
+	if err != nil {
+		e.Logger.Fatal(err)
+	} else if accessCheckWorkers < 1 {
+		e.Logger.Fatal("ACCESS_CHECK_WORKERS must be at least 1")
+	}
 
-		return c.JSON(http.StatusOK, &workspaces)
-	})
+	namespaces := workspaces.StartNamespaceCache(context.Background(), clientset)
 
-	e.GET("/workspaces/:ws", func(c echo.Context) error {
-		nameReq, _ := labels.NewRequirement(
-			"kubernetes.io/metadata.name", selection.In, []string{c.Param("ws")},
+	accessRules, err := loadAccessRules()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	workspaceRoles, err := loadRoles()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	workspaceTiers, err := loadTiers()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	// the ClusterRoles may be managed by other means when the service isn't
+	// allowed to
+	if err := workspaces.ApplyRoleTemplates(context.Background(), cl, workspaceRoles); err != nil {
+		e.Logger.Errorf("failed to apply the role templates: %v", err)
+	}
+
+	accessPolicy, err := accessPolicy(e)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	policyCheck := policy.Middleware(accessPolicy)
+
+	opts, err := signupOptions(
+		cl, accessPolicy, clusterInfo(context.Background(), e, cfg, cl),
+		workspaceTiers, workspaces.OwnerClusterRole(workspaceRoles),
+	)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	signupBackend, err := signup.New(getEnv("SIGNUP_BACKEND", defaultSignupBackend), opts)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	heartbeat, err := time.ParseDuration(getEnv("WATCH_HEARTBEAT_INTERVAL", "30s"))
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	workspaceLimit, err := strconv.Atoi(getEnv("WORKSPACE_LIMIT", "5"))
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	workspaceOpts := workspaces.Options{
+		Client:             cl,
+		Clientset:          clientset,
+		Namespaces:         namespaces,
+		Changes:            changes.NewLog(workspaceChangesHistory),
+		Signups:            signupBackend,
+		AccessRules:        accessRules,
+		Roles:              workspaceRoles,
+		Tiers:              workspaceTiers,
+		AccessCheckWorkers: accessCheckWorkers,
+		WorkspaceLimit:     workspaceLimit,
+		WatchHeartbeat:     heartbeat,
+	}
+	rbacInformers := informers.NewSharedInformerFactory(clientset, 0)
+	if err := accessCheckOptions(context.Background(), rbacInformers, &workspaceOpts); err != nil {
+		e.Logger.Fatal(err)
+	}
+	workspaces.RecordChanges(context.Background(), namespaces, rbacInformers, workspaceOpts.Changes)
+	workspaceOpts.RoleBindings = rbacInformers.Rbac().V1().RoleBindings().Lister()
+	rbacInformers.Start(context.Background().Done())
+	// the owners and the roles of the users are found from the RoleBindings
+	rbacInformers.WaitForCacheSync(context.Background().Done())
+	workspaceHandlers, err := workspaces.New(workspaceOpts)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	e.POST("/api/v1/signup", signupBackend.PostHandler, policyCheck)
+
+	e.GET("/api/v1/signup", signupBackend.GetHandler)
+
+	signupAdmin := e.Group("/api/v1/admin/signups")
+	if approvals, ok := signupBackend.(signup.ApprovalBackend); ok {
+		signupAdmin.GET(
+			"", approvals.ListHandler,
+			auth.RequirePermission(authCl, signupAdminPermission(opts.Namespace, "list")),
 		)
-		userNamespaces, err := getUserNamespaces(e, *nameReq)
-		if err != nil {
-			e.Logger.Fatal(err)
-		}
-		workspaces, err := getWorkspacesWithAccess(e, c, userNamespaces, getNamespacesWithAccess)
-		if err != nil {
-			e.Logger.Fatal(err)
-		}
-		wsParam := c.Param("ws")
-		for _, ws := range workspaces.Items {
-			if ws.Name == wsParam {
-				return c.JSON(http.StatusOK, &ws)
-			}
-		}
+		signupAdmin.POST(
+			"/:email/approve", approvals.ApproveHandler,
+			auth.RequirePermission(authCl, signupAdminPermission(opts.Namespace, "update")),
+		)
+		signupAdmin.POST(
+			"/:email/reject", approvals.RejectHandler,
+			auth.RequirePermission(authCl, signupAdminPermission(opts.Namespace, "update")),
+		)
+	}
+	if deactivations, ok := signupBackend.(signup.DeactivationBackend); ok {
+		e.DELETE("/api/v1/signup", deactivations.DeleteHandler)
+		signupAdmin.POST(
+			"/:email/deactivate", deactivations.DeactivateHandler,
+			auth.RequirePermission(authCl, signupAdminPermission(opts.Namespace, "update")),
+		)
+		signupAdmin.POST(
+			"/:email/reactivate", deactivations.ReactivateHandler,
+			auth.RequirePermission(authCl, signupAdminPermission(opts.Namespace, "update")),
+		)
+		go deactivations.ReapNamespaces(context.Background(), time.Minute, func(err error) {
+			e.Logger.Errorf("failed to delete the namespaces of deactivated users: %v", err)
+		})
+	}
 
-		return echo.NewHTTPError(http.StatusNotFound)
-	})
+	if verifications, ok := signupBackend.(signup.VerificationBackend); ok && opts.VerificationRequired {
+		e.POST("/api/v1/signup/verification", verifications.InitiateVerificationHandler, policyCheck)
+		e.POST("/api/v1/signup/verification/:code", verifications.VerifyHandler, policyCheck)
+	}
+
+	e.GET("/workspaces", workspaceHandlers.ListHandler, policyCheck)
+	e.POST("/workspaces", workspaceHandlers.CreateHandler, policyCheck)
+	e.GET("/workspaces/:ws", workspaceHandlers.GetHandler, policyCheck)
+	e.DELETE("/workspaces/:ws", workspaceHandlers.DeleteHandler, policyCheck)
+
+	e.GET("/api/v1/roles", workspaceHandlers.RolesHandler, policyCheck)
 
+	e.GET("/workspaces/:ws/members", workspaceHandlers.ListMembersHandler, policyCheck)
+	e.POST("/workspaces/:ws/members", workspaceHandlers.AddMemberHandler, policyCheck)
+	e.DELETE("/workspaces/:ws/members/:email", workspaceHandlers.RemoveMemberHandler, policyCheck)
+
+	e.PUT(
+		"/api/v1/admin/workspaces/:ws/tier", workspaceHandlers.ChangeTierHandler,
+		auth.RequirePermission(authCl, tierAdminPermission),
+	)
+
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	// the service is ready once the user namespaces are loaded
 	e.GET("/health", func(c echo.Context) error {
+		if !namespaces.HasSynced() {
+			return c.NoContent(http.StatusServiceUnavailable)
+		}
 		return c.NoContent(http.StatusOK)
 	})
 
@@ -1,34 +1,34 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
 
-	"github.com/labstack/echo/v4"
 	k8sapi "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/selection"
-	"k8s.io/client-go/kubernetes"
-	authorizationv1Client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 
 	"context"
-	"net/http/httptest"
 	"testing"
 
 	crt "github.com/codeready-toolchain/api/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/handlers/workspaces"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
 	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
 
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -45,74 +45,19 @@ type HTTPheader struct {
 	value string
 }
 
-type NamespaceRoleBinding struct {
-	Namespace   string
-	Role        string
-	RoleBinding string
-}
-
 var k8sClient client.Client
 var testEnv *envtest.Environment
 
-func createRole(k8sClient client.Client, nsName string, roleName string, verbs []string) {
-	role := &rbacv1.Role{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      roleName,
-			Namespace: nsName,
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{"appstudio.redhat.com"},
-				Resources: []string{"applications", "components"},
-				Verbs:     verbs,
-			},
-		},
-	}
-	err := k8sClient.Create(context.Background(), role)
-	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'Role' resource: %v", err))
-}
-
-func createRoleBinding(k8sClient client.Client, bindingName string, nsName string, userName string, roleName string) {
-	roleBinding := &rbacv1.RoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      bindingName,
-			Namespace: nsName,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:     "User",
-				Name:     userName,
-				APIGroup: "rbac.authorization.k8s.io",
-			},
-		},
-		RoleRef: rbacv1.RoleRef{
-			Kind:     "Role",
-			Name:     roleName,
-			APIGroup: "rbac.authorization.k8s.io",
-		},
-	}
-	err := k8sClient.Create(context.Background(), roleBinding)
-	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'roleBinding' resource: %v", err))
+func performHTTPGetCall(url string, header HTTPheader) (*HTTPResponse, error) {
+	return performHTTPCall("GET", url, header)
 }
 
-func createNamespace(k8sClient client.Client, name string) (k8sapi.Namespace, error) {
-	namespaced := &k8sapi.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
-			Labels: map[string]string{
-				"konflux.ci/type":             "user",
-				"kubernetes.io/metadata.name": name,
-			},
-		},
-	}
-	if err := k8sClient.Create(context.Background(), namespaced); err != nil {
-		return k8sapi.Namespace{}, fmt.Errorf("Error creating 'Namespace' resource: %v", err)
-	}
-	return *namespaced, nil
+func performHTTPPostCall(url string, header HTTPheader) (*HTTPResponse, error) {
+	return performHTTPCall("POST", url, header)
 }
 
-func performHTTPGetCall(url string, header HTTPheader) (*HTTPResponse, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func performHTTPCall(method string, url string, header HTTPheader) (*HTTPResponse, error) {
+	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		log.Printf("Error creating request: %s", err)
 		return nil, err
@@ -144,26 +89,49 @@ func TestCmd(t *testing.T) {
 	RunSpecs(t, "Main Suite")
 }
 
+var _ = Describe("Metrics endpoint", func() {
+	It("exposes the access decision cache metrics", func() {
+		resp, err := performHTTPGetCall("http://localhost:5000/metrics", HTTPheader{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body).To(ContainSubstring("workspace_manager_access_cache_entries"))
+	})
+})
+
 var _ = Describe("Signup endpoint", func() {
 	Context("Calling the signup endpoint with GET", func() {
 		It("responds with ready and signedup", func() {
 			url := "http://localhost:5000/api/v1/signup"
-			expectedCode := http.StatusOK
-			expectedBody := `{"status":{"ready":true,"reason":"SignedUp"}}`
-			resp, err := performHTTPGetCall(url, HTTPheader{})
+			header := HTTPheader{"X-Email", "signupuser@konflux.dev"}
+			resp, err := performHTTPPostCall(url, header)
 			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Unexpected error testing the \"%s\" endpoint: %v", url, err))
-			Expect(resp.StatusCode).To(Equal(expectedCode))
-			Expect(strings.TrimSpace(expectedBody)).To(Equal(strings.TrimSpace(resp.Body)))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			resp, err = performHTTPGetCall(url, header)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Unexpected error testing the \"%s\" endpoint: %v", url, err))
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			signup := &v1alpha1.Signup{}
+			Expect(json.Unmarshal([]byte(resp.Body), signup)).To(Succeed())
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.SignedUp))
+			Expect(signup.Username).To(Equal("signupuser@konflux.dev"))
+			Expect(signup.CompliantUsername).To(Equal("signupuser"))
+			Expect(signup.DefaultUserNamespace).To(Equal("signupuser-tenant"))
+			Expect(signup.APIEndpoint).To(Equal(testEnv.Config.Host))
 		})
 	})
 })
 
+// matches the resource version of the lists, which changes with the cluster
+var listResourceVersion = regexp.MustCompile(`"resourceVersion":"[^"]+"`)
+
 var _ = DescribeTable("Workspace endpoint", func(header HTTPheader, expectedCode int, expectedBody string) {
 	url := "http://localhost:5000/workspaces"
 	resp, err := performHTTPGetCall(url, header)
 	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Unexpected error testing the \"%s\" endpoint: %v", url, err))
 	Expect(resp.StatusCode).To(Equal(expectedCode))
-	Expect(strings.TrimSpace(expectedBody)).To(Equal(strings.TrimSpace(resp.Body)))
+	body := listResourceVersion.ReplaceAllString(resp.Body, "")
+	Expect(strings.TrimSpace(expectedBody)).To(Equal(strings.TrimSpace(body)))
 },
 	Entry(
 		"Calling the workspace endpoint for funcuser1 responds only with the 'func-test-tenant' workspace info",
@@ -172,7 +140,7 @@ var _ = DescribeTable("Workspace endpoint", func(header HTTPheader, expectedCode
 		`{"kind":"WorkspaceList","apiVersion":"toolchain.dev.openshift.com/v1alpha1","metadata":{},`+
 			`"items":[{"kind":"Workspace","apiVersion":"toolchain.dev.openshift.com/v1alpha1",`+
 			`"metadata":{"name":"func-test-tenant","creationTimestamp":null},"status":`+
-			`{"namespaces":[{"name":"func-test-tenant","type":"default"}]}}]}`),
+			`{"namespaces":[{"name":"func-test-tenant","type":"default"}],"role":"viewer"}}]}`),
 	Entry(
 		"Workspace endpoint for funcuser2 responds with 2 namespaces info",
 		HTTPheader{"X-Email", "funcuser2@konflux.dev"},
@@ -180,10 +148,10 @@ var _ = DescribeTable("Workspace endpoint", func(header HTTPheader, expectedCode
 		`{"kind":"WorkspaceList","apiVersion":"toolchain.dev.openshift.com/v1alpha1","metadata":{},`+
 			`"items":[{"kind":"Workspace","apiVersion":"toolchain.dev.openshift.com/v1alpha1",`+
 			`"metadata":{"name":"func-test-tenant","creationTimestamp":null},"status":{"namespaces":`+
-			`[{"name":"func-test-tenant","type":"default"}]}},{"kind":"Workspace","apiVersion":`+
+			`[{"name":"func-test-tenant","type":"default"}],"role":"viewer"}},{"kind":"Workspace","apiVersion":`+
 			`"toolchain.dev.openshift.com/v1alpha1","metadata":{"name":"func-test-tenant-2",`+
 			`"creationTimestamp":null},"status":{"namespaces":[{"name":"func-test-tenant-2",`+
-			`"type":"default"}]}}]}`),
+			`"type":"default"}],"role":"viewer"}}]}`),
 	Entry(
 		"Workspace endpoint for funcuser3 responds with no namespaces",
 		HTTPheader{"X-Email", "funcuser3@konflux.dev"},
@@ -192,10 +160,45 @@ var _ = DescribeTable("Workspace endpoint", func(header HTTPheader, expectedCode
 	Entry(
 		"Workspace endpoint with no header",
 		HTTPheader{},
-		500,
-		`{"message":"Internal Server Error"}`),
+		401,
+		`{"message":"Unauthorized"}`),
 )
 
+var _ = Describe("Workspace endpoint pagination", func() {
+	header := HTTPheader{"X-Email", "funcuser2@konflux.dev"}
+
+	It("pages through the workspaces with limit and continue", func() {
+		resp, err := performHTTPGetCall("http://localhost:5000/workspaces?limit=1", header)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		page := crt.WorkspaceList{}
+		Expect(json.Unmarshal([]byte(resp.Body), &page)).To(Succeed())
+		Expect(page.Items).To(HaveLen(1))
+		Expect(page.Items[0].Name).To(Equal("func-test-tenant"))
+		Expect(page.Continue).NotTo(BeEmpty())
+		Expect(page.ResourceVersion).NotTo(BeEmpty())
+
+		resp, err = performHTTPGetCall("http://localhost:5000/workspaces?limit=1&continue="+page.Continue, header)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		page = crt.WorkspaceList{}
+		Expect(json.Unmarshal([]byte(resp.Body), &page)).To(Succeed())
+		Expect(page.Items).To(HaveLen(1))
+		Expect(page.Items[0].Name).To(Equal("func-test-tenant-2"))
+	})
+
+	DescribeTable("rejecting invalid parameters",
+		func(query string) {
+			resp, err := performHTTPGetCall("http://localhost:5000/workspaces?"+query, header)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		},
+		Entry("with a negative limit", "limit=-1"),
+		Entry("with a limit that is not a number", "limit=many"),
+		Entry("with an invalid continue token", "continue=invalid"),
+	)
+})
+
 var _ = DescribeTable("Specific workspace endpoint", func(endpoint string, header HTTPheader, expectedCode int, expectedBody string) {
 	url := "http://localhost:5000/workspaces/" + endpoint
 	resp, err := performHTTPGetCall(url, header)
@@ -210,7 +213,7 @@ var _ = DescribeTable("Specific workspace endpoint", func(endpoint string, heade
 		http.StatusOK,
 		`{"kind":"Workspace","apiVersion":"toolchain.dev.openshift.com/v1alpha1","metadata":`+
 			`{"name":"func-test-tenant","creationTimestamp":null},"status":{"namespaces":`+
-			`[{"name":"func-test-tenant","type":"default"}]}}`),
+			`[{"name":"func-test-tenant","type":"default"}],"role":"viewer"}}`),
 	Entry(
 		"Specific workspace endpoint for func-test-tenant-2 for funcuser1 only",
 		"func-test-tenant-2",
@@ -225,24 +228,33 @@ var serverCancelFunc context.CancelFunc
 var _ = BeforeSuite(func() {
 	schema := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(schema))
-	testEnv = &envtest.Environment{BinaryAssetsDirectory: "../bin/k8s/1.29.0-linux-amd64/"}
+	utilruntime.Must(v1alpha1.AddToScheme(schema))
+	testEnv = &envtest.Environment{
+		BinaryAssetsDirectory: "../bin/k8s/1.29.0-linux-amd64/",
+		CRDDirectoryPaths:     []string{"../config/crd/bases"},
+		ErrorIfCRDPathMissing: true,
+	}
 	k8sClient = utils.StartTestEnv(schema, testEnv)
 
+	signupNamespace := &k8sapi.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "workspace-manager"}}
+	Expect(k8sClient.Create(context.Background(), signupNamespace)).To(Succeed())
 	serverProcess, serverCancelFunc = utils.CreateWorkspaceManagerServer("main.go", nil, "")
 	utils.WaitForWorkspaceManagerServerToServe()
+	// the server applies them as well, the specs bind the users to them
+	Expect(workspaces.ApplyRoleTemplates(context.Background(), k8sClient, roles.Default())).To(Succeed())
 
 	user1 := "funcuser1@konflux.dev"
 	user2 := "funcuser2@konflux.dev"
 	namespaceNames := []string{"func-test-tenant", "func-test-tenant-2", "func-test-tenant-3"}
 	for _, name := range namespaceNames {
-		_, err := createNamespace(k8sClient, name)
+		_, err := utils.CreateNamespace(k8sClient, name)
 		Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
 	}
-	createRole(k8sClient, "func-test-tenant", "func-namespace-access", []string{"create", "list", "watch", "delete"})
-	createRole(k8sClient, "func-test-tenant-2", "func-namespace-access-2", []string{"create", "list", "watch", "delete"})
-	createRoleBinding(k8sClient, "func-namespace-access-user-binding", "func-test-tenant", user1, "func-namespace-access")
-	createRoleBinding(k8sClient, "func-namespace-access-user-binding-2", "func-test-tenant", user2, "func-namespace-access")
-	createRoleBinding(k8sClient, "func-namespace-access-user-binding-3", "func-test-tenant-2", user2, "func-namespace-access-2")
+	utils.CreateRole(k8sClient, "func-test-tenant", "func-namespace-access", []string{"create", "list", "watch", "delete"})
+	utils.CreateRole(k8sClient, "func-test-tenant-2", "func-namespace-access-2", []string{"create", "list", "watch", "delete"})
+	utils.CreateRoleBinding(k8sClient, "func-namespace-access-user-binding", "func-test-tenant", user1, "func-namespace-access")
+	utils.CreateRoleBinding(k8sClient, "func-namespace-access-user-binding-2", "func-test-tenant", user2, "func-namespace-access")
+	utils.CreateRoleBinding(k8sClient, "func-namespace-access-user-binding-3", "func-test-tenant-2", user2, "func-namespace-access-2")
 })
 
 var _ = AfterSuite(func() {
@@ -250,398 +262,35 @@ var _ = AfterSuite(func() {
 	utils.StopEnvTest(testEnv)
 })
 
-var _ = Describe("TestRunAccessCheck", func() {
-	var authCl authorizationv1Client.AuthorizationV1Interface
-
-	BeforeEach(func() {
-		// Set up Kubernetes client
-		cfg, err := config.GetConfig()
-		Expect(err).NotTo(HaveOccurred(), "Unexpected error getting Kubernetes config")
-		clientset, err := kubernetes.NewForConfig(cfg)
-		Expect(err).NotTo(HaveOccurred(), "Unexpected error creating Kubernetes clientset")
-		authCl = clientset.AuthorizationV1()
-	})
-
-	Context("When a user has access to the resource", func() {
-		It("should return true for a user with 'create' permission on test-tenant", func() {
-			user := "user3@konflux.dev"
-			namespace := "test-tenant"
-			resource := "applications"
-			verb := "create"
-			expectedResult := true
-			_, err := createNamespace(k8sClient, namespace)
-			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", namespace, err))
-			createRole(k8sClient, "test-tenant", "namespace-access", []string{"create", "list", "watch", "delete"})
-			createRoleBinding(k8sClient, "namespace-access-user-binding", "test-tenant", user, "namespace-access")
-			boolresult, err := runAccessCheck(authCl, user, namespace, "appstudio.redhat.com", resource, verb)
-			Expect(boolresult).To(Equal(expectedResult))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing RunAccessCheck")
-		})
-	})
-
-	Context("When a user does not have any permissions on the namespace", func() {
-		It("should return false for a user without access to test-tenant-2", func() {
-			user := "user4@konflux.dev"
-			namespace := "test-tenant-2"
-			resource := "applications"
-			verb := "create"
-			expectedResult := false
-			_, err := createNamespace(k8sClient, namespace)
-			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", namespace, err))
-
-			createRole(k8sClient, "test-tenant-2", "namespace-access-2", []string{"create", "list", "watch", "delete"})
-			createRoleBinding(k8sClient, "namespace-access-user-binding-3", "test-tenant-2", user, "namespace-access-2")
-			boolresult, err := runAccessCheck(authCl, "user3@konflux.dev", namespace, "appstudio.redhat.com", resource, verb)
-			Expect(boolresult).To(Equal(expectedResult))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing RunAccessCheck")
-		})
-	})
-
-	Context("When a user lacks the specific action permission on the namespace", func() {
-		It("should return false for a user without 'patch' permission on test-tenant-1", func() {
-			user := "user5@konflux.dev"
-			namespace := "test-tenant-1"
-			resource := "applications"
-			verb := "patch"
-			expectedResult := false
-			_, err := createNamespace(k8sClient, namespace)
-			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", namespace, err))
-			createRole(k8sClient, "test-tenant-1", "namespace-access", []string{"create", "list", "watch", "delete"})
-			createRoleBinding(k8sClient, "namespace-access-user-binding", "test-tenant-1", user, "namespace-access")
-			boolresult, err := runAccessCheck(authCl, user, namespace, "appstudio.redhat.com", resource, verb)
-			Expect(boolresult).To(Equal(expectedResult))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing RunAccessCheck")
-		})
-	})
-})
-
-var _ = Describe("GetWorkspacesWithAccess querying for workspaces with access", func() {
-	var (
-		allNamespaces      []k8sapi.Namespace
-		expectedWorkspaces []crt.Workspace
-		e                  *echo.Echo
-		c                  echo.Context
-		gv                 string
-	)
-
-	BeforeEach(func() {
-		e = echo.New()
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		rec := httptest.NewRecorder()
-		c = e.NewContext(req, rec)
-		c.Request().Header.Set("X-Email", "user@konflux.dev")
-	})
-
-	Context("When workspace test-tenant's namespaces has all the necessary permissions", func() {
-		namespaceNames := []string{"ws-test-tenant-1", "ws-test-tenant-2"}
-		BeforeEach(func() {
-			gv = crt.GroupVersion.String()
-			for _, name := range namespaceNames {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
-				allNamespaces = append(allNamespaces, ns)
-			}
-			expectedWorkspaces = []crt.Workspace{
-				{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "Workspace",
-						APIVersion: gv,
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "ws-test-tenant-1",
-					},
-					Status: crt.WorkspaceStatus{
-						Namespaces: []crt.SpaceNamespace{
-							{
-								Name: "ws-test-tenant-1",
-								Type: "default",
-							},
-						},
-					},
-				},
-				{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "Workspace",
-						APIVersion: gv,
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "ws-test-tenant-2",
-					},
-					Status: crt.WorkspaceStatus{
-						Namespaces: []crt.SpaceNamespace{
-							{
-								Name: "ws-test-tenant-2",
-								Type: "default",
-							},
-						},
-					},
-				},
-			}
-		})
-		It("Should return a WorkspaceList with test-tenant workspace and both namespaces in it", func() {
-			mockNamespaceWithAccess := func(e *echo.Echo, c echo.Context, authCl authorizationv1Client.AuthorizationV1Interface, allNamespaces []k8sapi.Namespace) ([]k8sapi.Namespace, error) {
-				return []k8sapi.Namespace{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "ws-test-tenant-1",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "ws-test-tenant-2",
-						},
-					},
-				}, nil
-			}
-			actualWorkspaces, err := getWorkspacesWithAccess(e, c, allNamespaces, mockNamespaceWithAccess)
-			Expect(actualWorkspaces.Items).To(Equal(expectedWorkspaces))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
-		})
-	})
-
-	Context("When workspace with only ws-test-tenant-3 namespace has all the necessary permissions", func() {
-		namespaceNames := []string{"ws-test-tenant-3", "ws-test-tenant-4"}
-		BeforeEach(func() {
-			gv = crt.GroupVersion.String()
-			for _, name := range namespaceNames {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
-				allNamespaces = append(allNamespaces, ns)
-			}
-			expectedWorkspaces = []crt.Workspace{
-				{
-					TypeMeta: metav1.TypeMeta{
-						Kind:       "Workspace",
-						APIVersion: gv,
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "ws-test-tenant-3",
-					},
-					Status: crt.WorkspaceStatus{
-						Namespaces: []crt.SpaceNamespace{
-							{
-								Name: "ws-test-tenant-3",
-								Type: "default",
-							},
-						},
-					},
-				},
-			}
-		})
-		It("Should return a WorkspaceList with test-tenant workspace and only test-tenant namespace in it", func() {
-			mockNamespaceWithAccess := func(e *echo.Echo, c echo.Context, authCl authorizationv1Client.AuthorizationV1Interface, allNamespaces []k8sapi.Namespace) ([]k8sapi.Namespace, error) {
-				return []k8sapi.Namespace{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "ws-test-tenant-3",
-						},
-					},
-				}, nil
-			}
-			actualWorkspaces, err := getWorkspacesWithAccess(e, c, allNamespaces, mockNamespaceWithAccess)
-			Expect(actualWorkspaces.Items).To(Equal(expectedWorkspaces))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
-		})
-	})
-
-	Context("When no workspaces has all the necessary permissions", func() {
-		namespaceNames := []string{"ws-test-tenant-5", "ws-test-tenant-6"}
-		BeforeEach(func() {
-			for _, name := range namespaceNames {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
-				allNamespaces = append(allNamespaces, ns)
-			}
-		})
-		It("Should return a empty WorkspaceList", func() {
-			mockNamespaceWithAccess := func(e *echo.Echo, c echo.Context, authCl authorizationv1Client.AuthorizationV1Interface, allNamespaces []k8sapi.Namespace) ([]k8sapi.Namespace, error) {
-				return []k8sapi.Namespace{}, nil
-			}
-			actualWorkspaces, err := getWorkspacesWithAccess(e, c, allNamespaces, mockNamespaceWithAccess)
-			Expect(actualWorkspaces.Items).To(BeEmpty())
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
-		})
-	})
-})
-
-var _ = Describe("TestGetNamespacesWithAccess", func() {
-	var (
-		allNamespaces, actualNs, expectedNs []k8sapi.Namespace
-		err                                 error
-		mappings                            []NamespaceRoleBinding
-		e                                   *echo.Echo
-		authCl                              authorizationv1Client.AuthorizationV1Interface
-		c                                   echo.Context
-	)
-
-	BeforeEach(func() {
-		e = echo.New()
-		cfg, err := config.GetConfig()
-		Expect(err).NotTo(HaveOccurred(), "Error getting Kubernetes config")
-
-		clientset, err := kubernetes.NewForConfig(cfg)
-		Expect(err).NotTo(HaveOccurred(), "Error creating Kubernetes client")
-
-		authCl = clientset.AuthorizationV1()
-
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		rec := httptest.NewRecorder()
-		c = e.NewContext(req, rec)
-		c.Request().Header.Set("X-Email", "user@konflux.dev")
-	})
-
-	Context("When all the namespaces have the necessary permissions", func() {
-		mappings = []NamespaceRoleBinding{
-			{
-				Namespace:   "ns-test-tenant-1",
-				Role:        "ns-namespace-access-1",
-				RoleBinding: "ns-namespace-access-user-binding-1",
-			},
-			{
-				Namespace:   "ns-test-tenant-2",
-				Role:        "ns-namespace-access-2",
-				RoleBinding: "ns-namespace-access-user-binding-2",
-			},
+var _ = Describe("Role catalog", func() {
+	It("describes the roles", func() {
+		resp, err := performHTTPGetCall("http://localhost:5000/api/v1/roles", HTTPheader{"X-Email", "roles-user@konflux.dev"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		list := struct {
+			Items []struct {
+				Name        string              `json:"name"`
+				ClusterRole string              `json:"clusterRole"`
+				Rules       []rbacv1.PolicyRule `json:"rules"`
+			} `json:"items"`
+		}{}
+		Expect(json.Unmarshal([]byte(resp.Body), &list)).To(Succeed())
+		var names []string
+		for _, role := range list.Items {
+			names = append(names, role.Name)
 		}
-		BeforeEach(func() {
-			for _, name := range mappings {
-				ns, err := createNamespace(k8sClient, name.Namespace)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name.Namespace))
-				allNamespaces = append(allNamespaces, ns)
-				createRole(k8sClient, name.Namespace, name.Role, []string{"create", "list", "watch", "delete"})
-				createRoleBinding(k8sClient, name.RoleBinding, name.Namespace, "user@konflux.dev", name.Role)
-			}
-		})
-		It("returns all namespaces in the list", func() {
-			actualNs, err = getNamespacesWithAccess(e, c, authCl, allNamespaces)
-			Expect(actualNs).To(Equal(allNamespaces))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
-		})
-		AfterEach(func() {
-			allNamespaces = nil
-		})
+		Expect(names).To(Equal([]string{"viewer", "contributor", "maintainer", "admin"}))
+		Expect(list.Items[0].ClusterRole).To(Equal("konflux-viewer-user-actions"))
+		Expect(list.Items[0].Rules).NotTo(BeEmpty())
 	})
 
-	Context("When none of the namspaces have necessary permissions", func() {
-		var name = "ns-test-tenant-3"
-		BeforeEach(func() {
-			ns3, err := createNamespace(k8sClient, name)
-			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name))
-			allNamespaces = []k8sapi.Namespace{ns3}
-		})
-		It("doesn't return any namespace", func() {
-			actualNs, err = getNamespacesWithAccess(e, c, authCl, allNamespaces)
-			Expect(actualNs).To(BeEmpty())
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
-		})
-		AfterEach(func() {
-			allNamespaces = nil
-		})
-	})
-
-	Context("When namspace ns-test-tenant-5 doesn't have necessary permissions", func() {
-		BeforeEach(func() {
-			var names = []string{"ns-test-tenant-5", "ns-test-tenant-6"}
-			for _, name := range names {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name))
-				allNamespaces = append(allNamespaces, ns)
-				expectedNs = []k8sapi.Namespace{ns}
-			}
-			createRole(k8sClient, "ns-test-tenant-6", "ns-namespace-access-6", []string{"create", "list", "watch", "delete"})
-			createRoleBinding(k8sClient, "ns-namespace-access-user-binding-6", "ns-test-tenant-6", "user@konflux.dev", "ns-namespace-access-6")
-		})
-		It("only returns ns-test-tenant-6 namespace", func() {
-			actualNs, err = getNamespacesWithAccess(e, c, authCl, allNamespaces)
-			Expect(actualNs).To(Equal(expectedNs))
-			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
-		})
-		AfterEach(func() {
-			allNamespaces = nil
-		})
-	})
-})
-
-var _ = Describe("GetUserNamespaces", func() {
-	var e *echo.Echo
-	var createdNamespaces []string
-
-	// checks if all created namespaces are in the returned list
-	Context("When querying for all user namespaces using Exists", func() {
-		It("Should return all created namespaces", func() {
-			namesToCreate := []string{"test-ns-1", "test-ns-2", "test-ns-3"}
-			for _, name := range namesToCreate {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating the namespace %s", name))
-				createdNamespaces = append(createdNamespaces, ns.Name)
-			}
-
-			req, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.Exists, []string{})
-			Expect(err).NotTo(HaveOccurred(), "Error creating label requirement")
-
-			namespaces, err := getUserNamespaces(e, *req)
-			Expect(err).NotTo(HaveOccurred(), "Error getting user namespaces")
-
-			var actualNamespaces []string
-			for _, ns := range namespaces {
-				actualNamespaces = append(actualNamespaces, ns.Name)
-			}
-
-			for _, createdNs := range createdNamespaces {
-				Expect(actualNamespaces).To(ContainElement(createdNs))
-			}
-		})
-
-		AfterEach(func() {
-			createdNamespaces = nil
-		})
-	})
-	// checks if specific namespaces are in the returned list
-	Context("When querying for specific namespaces using In", func() {
-		It("Should return only the specified namespaces", func() {
-			for _, name := range []string{"in-test-1", "in-test-2", "not-in-test"} {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating the namespace %s", name))
-				createdNamespaces = append(createdNamespaces, ns.Name)
-			}
-
-			req, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.In, []string{"in-test-1", "in-test-2"})
-			Expect(err).NotTo(HaveOccurred(), "Error creating label requirement")
-
-			namespaces, err := getUserNamespaces(e, *req)
-			Expect(err).NotTo(HaveOccurred(), "Error getting user namespaces")
-
-			var actualNamespaces []string
-			for _, ns := range namespaces {
-				actualNamespaces = append(actualNamespaces, ns.Name)
-			}
-
-			Expect(actualNamespaces).To(ConsistOf("in-test-1", "in-test-2"))
-			Expect(actualNamespaces).NotTo(ContainElement("not-in-test"))
-		})
-	})
-
-	Context("When querying for namespaces using NotIn", func() {
-		It("Should return namespaces not in the specified list", func() {
-			for _, name := range []string{"ts-keep-1", "ts-keep-2", "ts-exclude-1", "ts-exclude-2"} {
-				ns, err := createNamespace(k8sClient, name)
-				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating the namespace %s", name))
-				createdNamespaces = append(createdNamespaces, ns.Name)
-			}
-
-			req, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.NotIn, []string{"ts-exclude-1", "ts-exclude-2"})
-			Expect(err).NotTo(HaveOccurred(), "Error creating label requirement")
-
-			namespaces, err := getUserNamespaces(e, *req)
-			Expect(err).NotTo(HaveOccurred(), "Error getting user namespaces")
-
-			var actualNamespaces []string
-			for _, ns := range namespaces {
-				actualNamespaces = append(actualNamespaces, ns.Name)
-			}
-
-			Expect(actualNamespaces).To(ContainElements("ts-keep-1", "ts-keep-2"))
-			Expect(actualNamespaces).NotTo(ContainElements("ts-exclude-1", "ts-exclude-2"))
-		})
+	It("requires the admin role in the roles file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "roles.yaml")
+		data := "roles:\n- name: viewer\n  clusterRole:\n    metadata: {name: roles-test-viewer}\n" +
+			"    rules: [{apiGroups: [appstudio.redhat.com], resources: [applications], verbs: [list]}]\n"
+		Expect(os.WriteFile(path, []byte(data), 0o600)).To(Succeed())
+		GinkgoT().Setenv("ROLES_FILE", path)
+		_, err := loadRoles()
+		Expect(err).To(MatchError("the admin role is not defined"))
 	})
 })
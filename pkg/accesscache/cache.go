@@ -0,0 +1,262 @@
+// Package accesscache caches the access decisions of the SubjectAccessReviews
+// so that polling clients don't trigger a review for every check
+package accesscache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	lookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workspace_manager_access_cache_lookups_total",
+			Help: "Lookups of access decisions in the cache, by result (hit or miss).",
+		},
+		[]string{"result"},
+	)
+	evictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "workspace_manager_access_cache_evictions_total",
+		Help: "Access decisions evicted from the cache because it was full.",
+	})
+	invalidations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "workspace_manager_access_cache_invalidations_total",
+		Help: "Invalidations of the access decisions of a namespace, or of all of them, after an RBAC change.",
+	})
+	entries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "workspace_manager_access_cache_entries",
+		Help: "Access decisions in the cache.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(lookups, evictions, invalidations, entries)
+}
+
+// Key identifies an access decision. The groups of the users are not part of
+// it since the decisions are made without them.
+type Key struct {
+	User      string
+	Namespace string
+	Group     string
+	Resource  string
+	Verb      string
+}
+
+// NewKey builds the key of the decision on whether the user can perform verb
+// on resource in namespace
+func NewKey(user string, namespace string, group string, resource string, verb string) Key {
+	return Key{
+		User:      user,
+		Namespace: namespace,
+		Group:     group,
+		Resource:  resource,
+		Verb:      verb,
+	}
+}
+
+// Options configures a Cache
+type Options struct {
+	// How long an allowed decision is kept
+	TTL time.Duration
+	// How long a denied decision is kept, usually shorter than TTL so that
+	// newly granted access shows up quickly
+	NegativeTTL time.Duration
+	// Maximum number of decisions kept, the least recently used ones are
+	// evicted first
+	MaxSize int
+	// How long after an invalidation the decisions of the namespace are not
+	// cached, the authorizer of the API server seeing the RBAC changes a bit
+	// after the watches
+	PropagationDelay time.Duration
+}
+
+// generation identifies the invalidations a decision was computed after
+type generation struct {
+	cluster   uint64
+	namespace uint64
+}
+
+type entry struct {
+	key     Key
+	allowed bool
+	expires time.Time
+}
+
+// Cache is a size bounded cache of access decisions with expiry
+type Cache struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries map[Key]*list.Element
+	// most recently used first
+	lru *list.List
+	// incremented on each invalidation of a namespace, or of all of them for
+	// the cluster one, decisions computed across an invalidation are not
+	// cached
+	generations       map[string]uint64
+	clusterGeneration uint64
+	// time of the last invalidation of a namespace, or of all of them
+	invalidated        map[string]time.Time
+	clusterInvalidated time.Time
+}
+
+// New creates an empty Cache
+func New(opts Options) *Cache {
+	return &Cache{
+		opts:        opts,
+		entries:     map[Key]*list.Element{},
+		lru:         list.New(),
+		generations: map[string]uint64{},
+		invalidated: map[string]time.Time{},
+	}
+}
+
+// Fetch returns the cached decision for key, or runs check and caches its
+// decision when it succeeds
+func (c *Cache) Fetch(key Key, check func() (bool, error)) (bool, error) {
+	allowed, found, computedAfter := c.get(key)
+	if found {
+		lookups.WithLabelValues("hit").Inc()
+		return allowed, nil
+	}
+	lookups.WithLabelValues("miss").Inc()
+	allowed, err := check()
+	if err != nil {
+		return false, err
+	}
+	c.add(key, allowed, computedAfter)
+	return allowed, nil
+}
+
+func (c *Cache) get(key Key) (bool, bool, generation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := generation{cluster: c.clusterGeneration, namespace: c.generations[key.Namespace]}
+	element, found := c.entries[key]
+	if !found {
+		return false, false, current
+	}
+	e := element.Value.(*entry)
+	if !time.Now().Before(e.expires) {
+		c.remove(element)
+		return false, false, current
+	}
+	c.lru.MoveToFront(element)
+	return e.allowed, true, current
+}
+
+func (c *Cache) add(key Key, allowed bool, computedAfter generation) {
+	ttl := c.opts.TTL
+	if !allowed {
+		ttl = c.opts.NegativeTTL
+	}
+	if ttl <= 0 || c.opts.MaxSize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := generation{cluster: c.clusterGeneration, namespace: c.generations[key.Namespace]}
+	if current != computedAfter {
+		// the namespace was invalidated while the decision was computed
+		return
+	}
+	if time.Since(c.invalidated[key.Namespace]) < c.opts.PropagationDelay ||
+		time.Since(c.clusterInvalidated) < c.opts.PropagationDelay {
+		// the decision may predate the change
+		return
+	}
+	if element, found := c.entries[key]; found {
+		c.remove(element)
+	}
+	for c.lru.Len() >= c.opts.MaxSize {
+		c.remove(c.lru.Back())
+		evictions.Inc()
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, allowed: allowed, expires: time.Now().Add(ttl)})
+	entries.Inc()
+}
+
+func (c *Cache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+	entries.Dec()
+}
+
+// InvalidateNamespace drops the decisions made for the namespace
+func (c *Cache) InvalidateNamespace(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[namespace]++
+	c.invalidated[namespace] = time.Now()
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*entry).key.Namespace == namespace {
+			c.remove(element)
+		}
+		element = next
+	}
+	invalidations.Inc()
+}
+
+// InvalidateAll drops all the decisions, as needed when the ClusterRoles or
+// ClusterRoleBindings change
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clusterGeneration++
+	c.clusterInvalidated = time.Now()
+	for element := c.lru.Front(); element != nil; element = c.lru.Front() {
+		c.remove(element)
+	}
+	invalidations.Inc()
+}
+
+// Len returns the number of decisions in the cache, including the expired
+// ones not evicted yet
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// InvalidateOnChange invalidates the decisions of a namespace whenever its
+// Roles or RoleBindings change, and all the decisions whenever a ClusterRole
+// or ClusterRoleBinding changes. The factory has to be started afterwards.
+func (c *Cache) InvalidateOnChange(factory informers.SharedInformerFactory) []cache.InformerSynced {
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.invalidateObject,
+		UpdateFunc: func(_, obj interface{}) { c.invalidateObject(obj) },
+		DeleteFunc: c.invalidateObject,
+	}
+	clusterHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { c.InvalidateAll() },
+		UpdateFunc: func(interface{}, interface{}) { c.InvalidateAll() },
+		DeleteFunc: func(interface{}) { c.InvalidateAll() },
+	}
+	roleBindings := factory.Rbac().V1().RoleBindings().Informer()
+	roleBindings.AddEventHandler(handler)
+	roles := factory.Rbac().V1().Roles().Informer()
+	roles.AddEventHandler(handler)
+	clusterRoleBindings := factory.Rbac().V1().ClusterRoleBindings().Informer()
+	clusterRoleBindings.AddEventHandler(clusterHandler)
+	clusterRoles := factory.Rbac().V1().ClusterRoles().Informer()
+	clusterRoles.AddEventHandler(clusterHandler)
+	return []cache.InformerSynced{
+		roleBindings.HasSynced, roles.HasSynced, clusterRoleBindings.HasSynced, clusterRoles.HasSynced,
+	}
+}
+
+func (c *Cache) invalidateObject(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if object, ok := obj.(interface{ GetNamespace() string }); ok {
+		c.InvalidateNamespace(object.GetNamespace())
+	}
+}
@@ -0,0 +1,186 @@
+package accesscache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accesscache"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestAccessCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Access decision cache Suite")
+}
+
+// check counting its calls and returning the given decision
+func counting(calls *int, allowed bool) func() (bool, error) {
+	return func() (bool, error) {
+		*calls++
+		return allowed, nil
+	}
+}
+
+func listKey(user string, namespace string) accesscache.Key {
+	return accesscache.NewKey(user, namespace, "appstudio.redhat.com", "applications", "list")
+}
+
+var _ = Describe("Cache", func() {
+	var calls int
+
+	BeforeEach(func() {
+		calls = 0
+	})
+
+	It("keeps the allowed decisions", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		for i := 0; i < 3; i++ {
+			allowed, err := c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeTrue())
+		}
+		Expect(calls).To(Equal(1))
+	})
+
+	It("keeps the denied decisions for the negative TTL", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: 50 * time.Millisecond, MaxSize: 10})
+		key := listKey("user@konflux.dev", "tenant")
+		_, _ = c.Fetch(key, counting(&calls, false))
+		_, _ = c.Fetch(key, counting(&calls, false))
+		Expect(calls).To(Equal(1))
+		time.Sleep(60 * time.Millisecond)
+		_, _ = c.Fetch(key, counting(&calls, false))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("separates the decisions of each key", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		allowed, _ := c.Fetch(listKey("other@konflux.dev", "tenant"), counting(&calls, false))
+		Expect(allowed).To(BeFalse())
+		createKey := accesscache.NewKey("user@konflux.dev", "tenant", "appstudio.redhat.com", "applications", "create")
+		_, _ = c.Fetch(createKey, counting(&calls, true))
+		Expect(calls).To(Equal(3))
+	})
+
+	It("does not keep the failed checks", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		_, err := c.Fetch(listKey("user@konflux.dev", "tenant"), func() (bool, error) {
+			return false, errors.New("unavailable")
+		})
+		Expect(err).To(MatchError("unavailable"))
+		Expect(c.Len()).To(Equal(0))
+	})
+
+	It("evicts the least recently used decisions when full", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 2})
+		_, _ = c.Fetch(listKey("a@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("b@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("a@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("c@konflux.dev", "tenant"), counting(&calls, true))
+		Expect(c.Len()).To(Equal(2))
+		Expect(calls).To(Equal(3))
+
+		_, _ = c.Fetch(listKey("a@konflux.dev", "tenant"), counting(&calls, true))
+		Expect(calls).To(Equal(3))
+		_, _ = c.Fetch(listKey("b@konflux.dev", "tenant"), counting(&calls, true))
+		Expect(calls).To(Equal(4))
+	})
+
+	It("drops the decisions of an invalidated namespace", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, false))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "other"), counting(&calls, false))
+		c.InvalidateNamespace("tenant")
+		Expect(c.Len()).To(Equal(1))
+		allowed, _ := c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		Expect(allowed).To(BeTrue())
+		Expect(calls).To(Equal(3))
+	})
+
+	It("does not keep a decision computed while its namespace was invalidated", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), func() (bool, error) {
+			c.InvalidateNamespace("tenant")
+			return false, nil
+		})
+		Expect(c.Len()).To(Equal(0))
+	})
+
+	It("drops all the decisions", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "other"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("other@konflux.dev", "tenant"), func() (bool, error) {
+			c.InvalidateAll()
+			return true, nil
+		})
+		Expect(c.Len()).To(Equal(0))
+	})
+
+	It("does not keep the decisions of a namespace invalidated within the propagation delay", func() {
+		c := accesscache.New(accesscache.Options{
+			TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10, PropagationDelay: 50 * time.Millisecond,
+		})
+		c.InvalidateNamespace("tenant")
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, false))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, false))
+		Expect(calls).To(Equal(2))
+		time.Sleep(60 * time.Millisecond)
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		Expect(calls).To(Equal(3))
+	})
+
+	It("is disabled without TTL", func() {
+		c := accesscache.New(accesscache.Options{MaxSize: 10})
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("invalidates a namespace when its RoleBindings change", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		clientset := fake.NewSimpleClientset()
+		factory := informers.NewSharedInformerFactory(clientset, 0)
+		synced := c.InvalidateOnChange(factory)
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		factory.Start(ctx.Done())
+		Expect(cache.WaitForCacheSync(ctx.Done(), synced...)).To(BeTrue())
+
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, false))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "other"), counting(&calls, false))
+		binding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "access", Namespace: "tenant"}}
+		_, err := clientset.RbacV1().RoleBindings("tenant").Create(ctx, binding, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(c.Len).Should(Equal(1))
+	})
+
+	It("invalidates all the namespaces when a ClusterRole changes", func() {
+		c := accesscache.New(accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+		clientset := fake.NewSimpleClientset()
+		factory := informers.NewSharedInformerFactory(clientset, 0)
+		synced := c.InvalidateOnChange(factory)
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		factory.Start(ctx.Done())
+		Expect(cache.WaitForCacheSync(ctx.Done(), synced...)).To(BeTrue())
+
+		_, _ = c.Fetch(listKey("user@konflux.dev", "tenant"), counting(&calls, true))
+		_, _ = c.Fetch(listKey("user@konflux.dev", "other"), counting(&calls, true))
+		clusterRole := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "konflux-viewer-user-actions"}}
+		_, err := clientset.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(c.Len).Should(Equal(0))
+	})
+})
@@ -0,0 +1,125 @@
+// Package accessrules defines which actions a user has to be allowed to
+// perform in a namespace to access it as a workspace
+package accessrules
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// How the checks of the rules are combined
+const (
+	// The user has to be allowed every check
+	MatchAll = "all"
+	// The user has to be allowed at least one check
+	MatchAny = "any"
+)
+
+// Rule lists the verbs the user has to be allowed on resources of an API group
+type Rule struct {
+	// API group of the resources, empty for the core group
+	Group     string   `json:"group"`
+	Resources []string `json:"resources"`
+	Verbs     []string `json:"verbs"`
+}
+
+// Config is the content of the access rules file
+type Config struct {
+	// Either all or any, all when not set
+	Match string `json:"match,omitempty"`
+	Rules []Rule `json:"rules"`
+}
+
+// Check is a single action the user may be allowed to perform in a namespace
+type Check struct {
+	Group    string
+	Resource string
+	Verb     string
+}
+
+// RuleSet is a compiled access rules Config
+type RuleSet struct {
+	any    bool
+	checks []Check
+}
+
+// Default returns the rules used when no file is configured: list and watch
+// on the applications and components of appstudio.redhat.com
+func Default() *RuleSet {
+	rules, _ := New(Config{
+		Match: MatchAll,
+		Rules: []Rule{{
+			Group:     "appstudio.redhat.com",
+			Resources: []string{"applications", "components"},
+			Verbs:     []string{"list", "watch"},
+		}},
+	})
+	return rules
+}
+
+// Load reads the access rules from the file at path
+func Load(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse compiles the YAML or JSON encoded access rules Config
+func Parse(data []byte) (*RuleSet, error) {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid access rules: %w", err)
+	}
+	return New(config)
+}
+
+// New compiles an access rules Config
+func New(config Config) (*RuleSet, error) {
+	r := &RuleSet{}
+	switch config.Match {
+	case "", MatchAll:
+	case MatchAny:
+		r.any = true
+	default:
+		return nil, fmt.Errorf("invalid access rules match %q, expected %s or %s", config.Match, MatchAll, MatchAny)
+	}
+	for i, rule := range config.Rules {
+		if len(rule.Resources) == 0 || len(rule.Verbs) == 0 {
+			return nil, fmt.Errorf("invalid access rule %d: resources and verbs are required", i)
+		}
+		for _, resource := range rule.Resources {
+			for _, verb := range rule.Verbs {
+				r.checks = append(r.checks, Check{Group: rule.Group, Resource: resource, Verb: verb})
+			}
+		}
+	}
+	if len(r.checks) == 0 {
+		return nil, fmt.Errorf("invalid access rules: no rule given")
+	}
+	return r, nil
+}
+
+// Checks returns the actions checked by the rules
+func (r *RuleSet) Checks() []Check {
+	return r.checks
+}
+
+// Evaluate decides whether the rules are satisfied, allowed reporting
+// whether the user is allowed a single check. The evaluation stops as soon
+// as the decision is known or allowed fails.
+func (r *RuleSet) Evaluate(allowed func(Check) (bool, error)) (bool, error) {
+	for _, check := range r.checks {
+		ok, err := allowed(check)
+		if err != nil {
+			return false, err
+		}
+		if ok == r.any {
+			return ok, nil
+		}
+	}
+	return !r.any, nil
+}
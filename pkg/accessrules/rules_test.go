@@ -0,0 +1,121 @@
+package accessrules_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+)
+
+func TestAccessRules(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Access rules Suite")
+}
+
+// allowing only the given checks
+func allowing(allowed ...accessrules.Check) func(accessrules.Check) (bool, error) {
+	return func(check accessrules.Check) (bool, error) {
+		for _, a := range allowed {
+			if a == check {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+var (
+	listApplications  = accessrules.Check{Group: "appstudio.redhat.com", Resource: "applications", Verb: "list"}
+	watchApplications = accessrules.Check{Group: "appstudio.redhat.com", Resource: "applications", Verb: "watch"}
+	listComponents    = accessrules.Check{Group: "appstudio.redhat.com", Resource: "components", Verb: "list"}
+	watchComponents   = accessrules.Check{Group: "appstudio.redhat.com", Resource: "components", Verb: "watch"}
+	getReleasePlans   = accessrules.Check{Group: "appstudio.redhat.com", Resource: "releaseplans", Verb: "get"}
+)
+
+var _ = Describe("Default rules", func() {
+	It("require list and watch on applications and components", func() {
+		Expect(accessrules.Default().Checks()).To(ConsistOf(
+			listApplications, watchApplications, listComponents, watchComponents,
+		))
+	})
+})
+
+var _ = DescribeTable("Evaluating the rules",
+	func(config string, allowed []accessrules.Check, expected bool) {
+		rules, err := accessrules.Parse([]byte(config))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rules.Evaluate(allowing(allowed...))).To(Equal(expected))
+	},
+	Entry(
+		"allows when all checks are allowed",
+		"rules:\n- group: appstudio.redhat.com\n  resources: [applications, components]\n  verbs: [list]",
+		[]accessrules.Check{listApplications, listComponents}, true,
+	),
+	Entry(
+		"denies when a check is denied",
+		"match: all\nrules:\n- group: appstudio.redhat.com\n  resources: [applications, components]\n  verbs: [list]",
+		[]accessrules.Check{listApplications}, false,
+	),
+	Entry(
+		"allows any of the checks",
+		"match: any\nrules:\n- group: appstudio.redhat.com\n  resources: [applications]\n  verbs: [list]\n"+
+			"- group: appstudio.redhat.com\n  resources: [releaseplans]\n  verbs: [get]",
+		[]accessrules.Check{getReleasePlans}, true,
+	),
+	Entry(
+		"denies when none of the checks is allowed",
+		"match: any\nrules:\n- group: appstudio.redhat.com\n  resources: [releaseplans]\n  verbs: [get]",
+		[]accessrules.Check{listApplications}, false,
+	),
+)
+
+var _ = DescribeTable("Parsing invalid rules",
+	func(config string, message string) {
+		_, err := accessrules.Parse([]byte(config))
+		Expect(err).To(MatchError(ContainSubstring(message)))
+	},
+	Entry("without rules", "match: all", "no rule given"),
+	Entry("with an unknown combinator", "match: some\nrules:\n- resources: [pods]\n  verbs: [get]", `match "some"`),
+	Entry("without verbs", "rules:\n- resources: [pods]", "resources and verbs are required"),
+	Entry("with an unknown field", "rules:\n- resource: pods\n  verbs: [get]", "invalid access rules"),
+)
+
+var _ = Describe("Evaluate", func() {
+	It("stops on the first error", func() {
+		rules := accessrules.Default()
+		calls := 0
+		_, err := rules.Evaluate(func(accessrules.Check) (bool, error) {
+			calls++
+			return false, errors.New("unavailable")
+		})
+		Expect(err).To(MatchError("unavailable"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("stops once the decision is known", func() {
+		rules := accessrules.Default()
+		calls := 0
+		allowed, err := rules.Evaluate(func(accessrules.Check) (bool, error) {
+			calls++
+			return false, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeFalse())
+		Expect(calls).To(Equal(1))
+	})
+})
+
+var _ = Describe("Load", func() {
+	It("reads the rules from a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "rules.yaml")
+		Expect(os.WriteFile(path, []byte("rules:\n- resources: [pods]\n  verbs: [get]"), 0o600)).To(Succeed())
+		rules, err := accessrules.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rules.Checks()).To(Equal([]accessrules.Check{{Resource: "pods", Verb: "get"}}))
+	})
+})
@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SignupApprovalState = string
+
+// The signup waits for an administrator
+var ApprovalPending SignupApprovalState = "pending"
+
+// The signup was approved and the user can be provisioned
+var ApprovalApproved SignupApprovalState = "approved"
+
+// The signup was rejected
+var ApprovalRejected SignupApprovalState = "rejected"
+
+// SignupApproval describes the approval of the signup of a user
+type SignupApproval struct {
+	Email string `json:"email"`
+	// Username assigned to the user, its default namespace is derived from it
+	CompliantUsername string              `json:"compliantUsername,omitempty"`
+	State             SignupApprovalState `json:"state"`
+	// Time at which the user signed up
+	RequestedAt metav1.Time `json:"requestedAt"`
+	// Time at which the signup was approved or rejected
+	DecidedAt *metav1.Time `json:"decidedAt,omitempty"`
+	// Administrator who approved or rejected the signup. Empty when the
+	// signup was approved automatically.
+	DecidedBy string `json:"decidedBy,omitempty"`
+	// Explanation given for the decision
+	Reason string `json:"reason,omitempty"`
+	// Time at which the access of the user was revoked
+	DeactivatedAt *metav1.Time `json:"deactivatedAt,omitempty"`
+	// User who revoked the access, either the user itself or an administrator
+	DeactivatedBy string `json:"deactivatedBy,omitempty"`
+	// Time at which the user verified its email
+	VerifiedAt *metav1.Time `json:"verifiedAt,omitempty"`
+	// Verification code sent to the user, not reported through the API
+	Verification *SignupVerification `json:"-"`
+	// Version of the stored record, a record changed since it was read can't
+	// be updated
+	ResourceVersion string `json:"-"`
+}
+
+type SignupApprovalList struct {
+	Items []SignupApproval `json:"items"`
+}
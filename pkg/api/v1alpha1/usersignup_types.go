@@ -1,14 +1,153 @@
 package v1alpha1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 type SignupStatusReason = string
 
+// The user is signed up and can use its workspace
 var SignedUp SignupStatusReason = "SignedUp"
 
+// The user workspace is being created
+var Provisioning SignupStatusReason = "Provisioning"
+
+// The signup waits for an administrator to approve it
+var PendingApproval SignupStatusReason = "PendingApproval"
+
+// An administrator rejected the signup
+var Rejected SignupStatusReason = "Rejected"
+
+// The user has to verify its email before the signup can proceed
+var VerificationRequired SignupStatusReason = "VerificationRequired"
+
+// The user was signed up but its access has been revoked
+var Deactivated SignupStatusReason = "Deactivated"
+
+// The user is not allowed to sign up
+var Banned SignupStatusReason = "Banned"
+
+// Types of the conditions reported in SignupStatus.Conditions
+const (
+	// An administrator, or an auto-approval rule, approved the signup
+	SignupApproved = "Approved"
+	// The access of the user was revoked
+	SignupDeactivated = "Deactivated"
+	// The user namespace exists and the user is bound to it
+	SignupProvisioned = "Provisioned"
+	// The user can use its workspace
+	SignupReady = "Ready"
+	// The user verified its email
+	SignupVerified = "Verified"
+)
+
 type SignupStatus struct {
 	Ready  bool               `json:"ready"`
 	Reason SignupStatusReason `json:"reason"`
+	// Human readable explanation of the reason
+	Message string `json:"message,omitempty"`
+	// Time at which the user signed up
+	CreationTimestamp *metav1.Time `json:"creationTimestamp,omitempty"`
+	// Detailed state of the signup
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// Signup is the response of GET /api/v1/signup. Its fields follow the
+// signup response of the codeready-toolchain registration service.
 type Signup struct {
+	// URL of the web console of the cluster, set once the signup is ready
+	ConsoleURL string `json:"consoleURL,omitempty"`
+	// URL of the Che dashboard, set once the signup is ready
+	CheDashboardURL string `json:"cheDashboardURL,omitempty"`
+	// URL of the API server of the cluster, set once the signup is ready
+	APIEndpoint string `json:"apiEndpoint,omitempty"`
+	// Name under which the user is known to the cluster
+	Username string `json:"username,omitempty"`
+	// DNS-1123 compliant name derived from the user email
+	CompliantUsername string `json:"compliantUsername,omitempty"`
+	// Namespace provisioned for the user, set once it is ready
+	DefaultUserNamespace string `json:"defaultUserNamespace,omitempty"`
+	// Profile of the user, as passed by the authenticating proxy
+	GivenName    string       `json:"givenName,omitempty"`
+	FamilyName   string       `json:"familyName,omitempty"`
+	Company      string       `json:"company,omitempty"`
 	SignupStatus SignupStatus `json:"status"`
 }
+
+// UserSignupSpec holds the state of the signup of a user
+type UserSignupSpec struct {
+	// Email of the user, as given by the authenticating proxy
+	Email string `json:"email"`
+	// Username assigned to the user, its default namespace is derived from it
+	// +optional
+	CompliantUsername string `json:"compliantUsername,omitempty"`
+	// Approval state of the signup
+	// +kubebuilder:validation:Enum=pending;approved;rejected
+	State SignupApprovalState `json:"state"`
+	// Time at which the signup was approved or rejected
+	// +optional
+	DecidedAt *metav1.Time `json:"decidedAt,omitempty"`
+	// Administrator who approved or rejected the signup. Empty when the
+	// signup was approved automatically.
+	// +optional
+	DecidedBy string `json:"decidedBy,omitempty"`
+	// Explanation given for the decision
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Time at which the access of the user was revoked
+	// +optional
+	DeactivatedAt *metav1.Time `json:"deactivatedAt,omitempty"`
+	// User who revoked the access, either the user itself or an administrator
+	// +optional
+	DeactivatedBy string `json:"deactivatedBy,omitempty"`
+	// Time at which the user verified its email
+	// +optional
+	VerifiedAt *metav1.Time `json:"verifiedAt,omitempty"`
+	// Verification code sent to the user
+	// +optional
+	Verification *SignupVerification `json:"verification,omitempty"`
+}
+
+// SignupVerification tracks the verification code sent to a user
+type SignupVerification struct {
+	// SHA-256 of the code sent to the user
+	CodeHash string `json:"codeHash"`
+	// Time at which the last code was sent
+	SentAt metav1.Time `json:"sentAt"`
+	// Time after which the code can't be used anymore
+	ExpiresAt metav1.Time `json:"expiresAt"`
+	// Number of wrong codes submitted for the current code
+	Attempts int `json:"attempts"`
+	// Start of the period over which the codes sent are counted
+	WindowStart metav1.Time `json:"windowStart"`
+	// Number of codes sent since WindowStart
+	Sent int `json:"sent"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Email",type="string",JSONPath=`.spec.email`
+//+kubebuilder:printcolumn:name="Username",type="string",JSONPath=`.spec.compliantUsername`
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=`.spec.state`
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// UserSignup records the signup of a user. Its creation time is the time
+// at which the user signed up.
+type UserSignup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec UserSignupSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// UserSignupList contains a list of UserSignup
+type UserSignupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UserSignup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UserSignup{}, &UserSignupList{})
+}
@@ -0,0 +1,231 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Signup) DeepCopyInto(out *Signup) {
+	*out = *in
+	in.SignupStatus.DeepCopyInto(&out.SignupStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Signup.
+func (in *Signup) DeepCopy() *Signup {
+	if in == nil {
+		return nil
+	}
+	out := new(Signup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignupApproval) DeepCopyInto(out *SignupApproval) {
+	*out = *in
+	in.RequestedAt.DeepCopyInto(&out.RequestedAt)
+	if in.DecidedAt != nil {
+		in, out := &in.DecidedAt, &out.DecidedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.DeactivatedAt != nil {
+		in, out := &in.DeactivatedAt, &out.DeactivatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.VerifiedAt != nil {
+		in, out := &in.VerifiedAt, &out.VerifiedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(SignupVerification)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignupApproval.
+func (in *SignupApproval) DeepCopy() *SignupApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(SignupApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignupApprovalList) DeepCopyInto(out *SignupApprovalList) {
+	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SignupApproval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignupApprovalList.
+func (in *SignupApprovalList) DeepCopy() *SignupApprovalList {
+	if in == nil {
+		return nil
+	}
+	out := new(SignupApprovalList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignupStatus) DeepCopyInto(out *SignupStatus) {
+	*out = *in
+	if in.CreationTimestamp != nil {
+		in, out := &in.CreationTimestamp, &out.CreationTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignupStatus.
+func (in *SignupStatus) DeepCopy() *SignupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SignupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignupVerification) DeepCopyInto(out *SignupVerification) {
+	*out = *in
+	in.SentAt.DeepCopyInto(&out.SentAt)
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+	in.WindowStart.DeepCopyInto(&out.WindowStart)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignupVerification.
+func (in *SignupVerification) DeepCopy() *SignupVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SignupVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSignup) DeepCopyInto(out *UserSignup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSignup.
+func (in *UserSignup) DeepCopy() *UserSignup {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSignup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UserSignup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSignupList) DeepCopyInto(out *UserSignupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UserSignup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSignupList.
+func (in *UserSignupList) DeepCopy() *UserSignupList {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSignupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UserSignupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSignupSpec) DeepCopyInto(out *UserSignupSpec) {
+	*out = *in
+	if in.DecidedAt != nil {
+		in, out := &in.DecidedAt, &out.DecidedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.DeactivatedAt != nil {
+		in, out := &in.DeactivatedAt, &out.DeactivatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.VerifiedAt != nil {
+		in, out := &in.VerifiedAt, &out.VerifiedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(SignupVerification)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSignupSpec.
+func (in *UserSignupSpec) DeepCopy() *UserSignupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSignupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationv1Client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// Header in which the authenticating proxy passes the email of the calling user
+const EmailHeader = "X-Email"
+
+// Headers in which the authenticating proxy may pass the profile of the calling user
+const (
+	UsernameHeader   = "X-Username"
+	GivenNameHeader  = "X-Given-Name"
+	FamilyNameHeader = "X-Family-Name"
+	CompanyHeader    = "X-Company"
+)
+
+// Email returns the email of the calling user, or an empty string if the
+// request is not authenticated
+func Email(c echo.Context) string {
+	return c.Request().Header.Get(EmailHeader)
+}
+
+// Username returns the name under which the calling user is known to the
+// cluster, its email unless the authenticating proxy passes another name
+func Username(c echo.Context) string {
+	if name := c.Request().Header.Get(UsernameHeader); name != "" {
+		return name
+	}
+	return Email(c)
+}
+
+// RequirePermission only lets through the requests of users allowed to
+// perform the action described by attrs
+func RequirePermission(
+	authCl authorizationv1Client.AuthorizationV1Interface,
+	attrs authorizationv1.ResourceAttributes,
+) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			email := Email(c)
+			if email == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+			resourceAttributes := attrs
+			sar := &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User:               email,
+					ResourceAttributes: &resourceAttributes,
+				},
+			}
+			response, err := authCl.SubjectAccessReviews().Create(
+				c.Request().Context(), sar, metav1.CreateOptions{},
+			)
+			if err != nil {
+				c.Logger().Error(err)
+				return echo.NewHTTPError(http.StatusInternalServerError)
+			}
+			if !response.Status.Allowed {
+				return echo.NewHTTPError(http.StatusForbidden)
+			}
+			return next(c)
+		}
+	}
+}
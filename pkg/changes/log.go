@@ -0,0 +1,119 @@
+// Package changes keeps a bounded log of the keys of the objects that changed,
+// numbered by resource versions from which watchers can resume
+package changes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrExpired is returned for a resource version whose changes are no longer
+// kept, or that was not issued by this log
+var ErrExpired = errors.New("the resource version is too old")
+
+type change struct {
+	seq uint64
+	key string
+}
+
+// Log records the keys of the changed objects, each change getting the next
+// resource version. The resource versions are opaque strings of the form
+// <epoch>-<sequence>, the epoch telling apart the logs of different runs.
+type Log struct {
+	epoch    string
+	capacity int
+
+	mu  sync.Mutex
+	seq uint64
+	// oldest first, between capacity and twice capacity changes are kept
+	changes     []change
+	subscribers map[chan struct{}]struct{}
+}
+
+// NewLog creates a Log keeping at least the last capacity changes
+func NewLog(capacity int) *Log {
+	return &Log{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		capacity:    capacity,
+		subscribers: map[chan struct{}]struct{}{},
+	}
+}
+
+// Record adds a change of the object with the given key and notifies the
+// subscribers
+func (l *Log) Record(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	l.changes = append(l.changes, change{seq: l.seq, key: key})
+	if len(l.changes) >= 2*l.capacity {
+		l.changes = append([]change(nil), l.changes[len(l.changes)-l.capacity:]...)
+	}
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- struct{}{}:
+		default:
+			// already notified
+		}
+	}
+}
+
+// ResourceVersion returns the resource version of the last change
+func (l *Log) ResourceVersion() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.resourceVersion()
+}
+
+func (l *Log) resourceVersion() string {
+	return fmt.Sprintf("%s-%d", l.epoch, l.seq)
+}
+
+// Since returns the keys of the objects changed after the given resource
+// version, each once in the order of their first change, along with the
+// resource version of the last change. ErrExpired is returned if some of the
+// changes are no longer kept.
+func (l *Log) Since(resourceVersion string) ([]string, string, error) {
+	epoch, seqValue, found := strings.Cut(resourceVersion, "-")
+	if !found || epoch != l.epoch {
+		return nil, "", ErrExpired
+	}
+	seq, err := strconv.ParseUint(seqValue, 10, 64)
+	if err != nil {
+		return nil, "", ErrExpired
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if seq > l.seq || (len(l.changes) > 0 && l.changes[0].seq > seq+1) {
+		return nil, "", ErrExpired
+	}
+	var keys []string
+	seen := map[string]bool{}
+	for _, c := range l.changes {
+		if c.seq > seq && !seen[c.key] {
+			seen[c.key] = true
+			keys = append(keys, c.key)
+		}
+	}
+	return keys, l.resourceVersion(), nil
+}
+
+// Subscribe returns a channel receiving a value after changes are recorded,
+// several changes may be notified by a single value. The returned function
+// cancels the subscription.
+func (l *Log) Subscribe() (<-chan struct{}, func()) {
+	subscriber := make(chan struct{}, 1)
+	l.mu.Lock()
+	l.subscribers[subscriber] = struct{}{}
+	l.mu.Unlock()
+	return subscriber, func() {
+		l.mu.Lock()
+		delete(l.subscribers, subscriber)
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,75 @@
+package changes_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/changes"
+)
+
+func TestChanges(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Change log Suite")
+}
+
+var _ = Describe("Log", func() {
+	It("returns the keys changed since a resource version", func() {
+		log := changes.NewLog(10)
+		log.Record("a")
+		start := log.ResourceVersion()
+		log.Record("b")
+		log.Record("c")
+		log.Record("b")
+
+		keys, current, err := log.Since(start)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"b", "c"}))
+		Expect(current).To(Equal(log.ResourceVersion()))
+
+		keys, _, err = log.Since(current)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(BeEmpty())
+	})
+
+	It("expires the resource versions of the changes no longer kept", func() {
+		log := changes.NewLog(2)
+		start := log.ResourceVersion()
+		for _, key := range []string{"a", "b", "c", "d"} {
+			log.Record(key)
+		}
+		_, _, err := log.Since(start)
+		Expect(err).To(MatchError(changes.ErrExpired))
+	})
+
+	It("rejects the resource versions it did not issue", func() {
+		log := changes.NewLog(2)
+		log.Record("a")
+		epoch, _, _ := strings.Cut(log.ResourceVersion(), "-")
+		for _, resourceVersion := range []string{
+			changes.NewLog(2).ResourceVersion() + "0",
+			"malformed",
+			epoch + "-x",
+			// from the future
+			epoch + "-2",
+		} {
+			_, _, err := log.Since(resourceVersion)
+			Expect(err).To(MatchError(changes.ErrExpired), resourceVersion)
+		}
+	})
+
+	It("notifies the subscribers", func() {
+		log := changes.NewLog(2)
+		notifications, cancel := log.Subscribe()
+		log.Record("a")
+		log.Record("b")
+		Eventually(notifications).Should(Receive())
+		Consistently(notifications).ShouldNot(Receive())
+
+		cancel()
+		log.Record("c")
+		Consistently(notifications).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,79 @@
+package namespace
+
+import (
+	"net/http"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// List the signups in the state given by the state query parameter, the
+// pending ones by default
+func (b *backend) ListHandler(c echo.Context) error {
+	state := c.QueryParam("state")
+	if state == "" {
+		state = v1alpha1.ApprovalPending
+	}
+	switch state {
+	case v1alpha1.ApprovalPending, v1alpha1.ApprovalApproved, v1alpha1.ApprovalRejected:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "unknown signup state "+state)
+	}
+	records, err := b.records.list(c.Request().Context(), state)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return c.JSON(http.StatusOK, &v1alpha1.SignupApprovalList{Items: records})
+}
+
+// Approve the signup of a user and provision its namespace, once the user
+// verified its email when verifications are required. Deactivated users have
+// to be reactivated instead.
+func (b *backend) ApproveHandler(c echo.Context) error {
+	record, err := b.decide(c, v1alpha1.ApprovalApproved)
+	if err != nil {
+		return err
+	}
+	if !b.verified(record) {
+		return c.String(http.StatusOK, "ok")
+	}
+	return b.provision(c, record)
+}
+
+// Reject the signup of a user. The reason query parameter is reported to
+// the user.
+func (b *backend) RejectHandler(c echo.Context) error {
+	if _, err := b.decide(c, v1alpha1.ApprovalRejected); err != nil {
+		return err
+	}
+	return c.String(http.StatusOK, "ok")
+}
+
+// Record the decision of the calling administrator on a signup
+func (b *backend) decide(c echo.Context, state v1alpha1.SignupApprovalState) (*v1alpha1.SignupApproval, error) {
+	ctx := c.Request().Context()
+	record, err := b.records.get(ctx, c.Param("email"))
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound)
+	}
+	// the namespace of a deactivated user would be provisioned again
+	if state == v1alpha1.ApprovalApproved && record.DeactivatedAt != nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, "the user is deactivated, reactivate it first")
+	}
+	now := metav1.Now()
+	record.State = state
+	record.DecidedAt = &now
+	record.DecidedBy = auth.Email(c)
+	record.Reason = c.QueryParam("reason")
+	if err := b.records.update(ctx, record); err != nil {
+		return nil, updateError(c, err)
+	}
+	return record, nil
+}
@@ -0,0 +1,284 @@
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotation holding the time after which the namespace of a deactivated
+// user is deleted
+const DeleteAfterAnnotation = "konflux.ci/delete-after"
+
+// Deactivate the calling user
+func (b *backend) DeleteHandler(c echo.Context) error {
+	email := auth.Email(c)
+	if email == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	return b.deactivate(c, email)
+}
+
+// Deactivate the user given in the email path parameter
+func (b *backend) DeactivateHandler(c echo.Context) error {
+	return b.deactivate(c, c.Param("email"))
+}
+
+// Restore the access of the user given in the email path parameter
+func (b *backend) ReactivateHandler(c echo.Context) error {
+	record, err := b.records.get(c.Request().Context(), c.Param("email"))
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record == nil {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	return b.reactivate(c, record)
+}
+
+// Mark the user as deactivated and revoke its access to all the user namespaces
+func (b *backend) deactivate(c echo.Context, email string) error {
+	ctx := c.Request().Context()
+	record, err := b.records.get(ctx, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record == nil {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	if record.DeactivatedAt == nil {
+		now := metav1.Now()
+		record.DeactivatedAt = &now
+		record.DeactivatedBy = auth.Email(c)
+		if err := b.records.update(ctx, record); err != nil {
+			return updateError(c, err)
+		}
+	}
+	deleteAfter := record.DeactivatedAt.Add(b.opts.DeactivationGracePeriod)
+	if err := revokeAccess(ctx, b.client, email, deleteAfter); err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return c.String(http.StatusOK, "ok")
+}
+
+// Clear the deactivation of the user, provision its namespace again and
+// restore its access to all the namespaces it owns. The access the user had
+// to namespaces owned by others is not restored.
+func (b *backend) reactivate(c echo.Context, record *v1alpha1.SignupApproval) error {
+	ctx := c.Request().Context()
+	if record.DeactivatedAt != nil {
+		record.DeactivatedAt = nil
+		record.DeactivatedBy = ""
+		if err := b.records.update(ctx, record); err != nil {
+			return updateError(c, err)
+		}
+	}
+	if err := retainNamespaces(ctx, b.client, record.Email); err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record.State != v1alpha1.ApprovalApproved || !b.verified(record) {
+		return c.String(http.StatusOK, "ok")
+	}
+	if err := b.provisionNamespace(c, record); err != nil {
+		return err
+	}
+	if err := restoreAccess(ctx, b.client, record.Email, b.opts.OwnerRole, b.opts.Tiers); err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return c.String(http.StatusOK, "ok")
+}
+
+// Delete the namespaces retained for deactivated users once their grace
+// period expired. Runs every interval until ctx is done.
+func (b *backend) ReapNamespaces(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := reapNamespaces(ctx, b.client, time.Now()); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Build the status of a deactivated user
+func deactivatedStatus(record *v1alpha1.SignupApproval, gracePeriod time.Duration) v1alpha1.SignupStatus {
+	message := fmt.Sprintf(
+		"access revoked on %s, owned namespaces are deleted after %s",
+		record.DeactivatedAt.UTC().Format(time.RFC3339),
+		record.DeactivatedAt.Add(gracePeriod).UTC().Format(time.RFC3339),
+	)
+	return v1alpha1.SignupStatus{
+		Reason:            v1alpha1.Deactivated,
+		Message:           message,
+		CreationTimestamp: &record.RequestedAt,
+		Conditions: []metav1.Condition{
+			approvedCondition(record),
+			{
+				Type:               v1alpha1.SignupDeactivated,
+				Status:             metav1.ConditionTrue,
+				Reason:             "Deactivated",
+				Message:            message,
+				LastTransitionTime: *record.DeactivatedAt,
+			},
+		},
+	}
+}
+
+func listUserNamespaces(ctx context.Context, cl client.Client) ([]core.Namespace, error) {
+	namespaces := &core.NamespaceList{}
+	if err := cl.List(ctx, namespaces, client.MatchingLabels{TypeLabel: UserType}); err != nil {
+		return nil, err
+	}
+	return namespaces.Items, nil
+}
+
+// Remove the user from the RoleBindings of all the user namespaces and
+// schedule the deletion of the namespaces it owns
+func revokeAccess(ctx context.Context, cl client.Client, email string, deleteAfter time.Time) error {
+	namespaces, err := listUserNamespaces(ctx, cl)
+	if err != nil {
+		return err
+	}
+	userNamespaces := map[string]bool{}
+	for i := range namespaces {
+		ns := &namespaces[i]
+		userNamespaces[ns.Name] = true
+		if !strings.EqualFold(ns.Annotations[OwnerAnnotation], email) {
+			continue
+		}
+		if !deleteAfter.After(time.Now()) {
+			if err := cl.Delete(ctx, ns); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+		if _, found := ns.Annotations[DeleteAfterAnnotation]; !found {
+			patch := client.MergeFrom(ns.DeepCopy())
+			ns.Annotations[DeleteAfterAnnotation] = deleteAfter.UTC().Format(time.RFC3339)
+			if err := cl.Patch(ctx, ns, patch); err != nil {
+				return err
+			}
+		}
+	}
+
+	bindings := &rbacv1.RoleBindingList{}
+	if err := cl.List(ctx, bindings); err != nil {
+		return err
+	}
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if !userNamespaces[binding.Namespace] {
+			continue
+		}
+		var subjects []rbacv1.Subject
+		for _, subject := range binding.Subjects {
+			if subject.Kind != rbacv1.UserKind || !strings.EqualFold(subject.Name, email) {
+				subjects = append(subjects, subject)
+			}
+		}
+		if len(subjects) == len(binding.Subjects) {
+			continue
+		}
+		if len(subjects) == 0 {
+			err = cl.Delete(ctx, binding)
+		} else {
+			binding.Subjects = subjects
+			err = cl.Update(ctx, binding)
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bind the user again to the owner roles in all the user namespaces it owns and
+// apply their tier again, the deactivation having removed the user from the
+// RoleBindings of the tier
+func restoreAccess(ctx context.Context, cl client.Client, email string, ownerRole string, catalog *tiers.Catalog) error {
+	namespaces, err := listUserNamespaces(ctx, cl)
+	if err != nil {
+		return err
+	}
+	for i := range namespaces {
+		ns := &namespaces[i]
+		if !strings.EqualFold(ns.Annotations[OwnerAnnotation], email) || ns.DeletionTimestamp != nil {
+			continue
+		}
+		if err := bindOwner(ctx, cl, ns.Name, email, ownerRole); err != nil {
+			return err
+		}
+		if err := reapplyTier(ctx, cl, ns.Name, email, catalog); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cancel the scheduled deletion of the namespaces owned by the user
+func retainNamespaces(ctx context.Context, cl client.Client, email string) error {
+	namespaces, err := listUserNamespaces(ctx, cl)
+	if err != nil {
+		return err
+	}
+	for i := range namespaces {
+		ns := &namespaces[i]
+		if !strings.EqualFold(ns.Annotations[OwnerAnnotation], email) {
+			continue
+		}
+		if _, found := ns.Annotations[DeleteAfterAnnotation]; !found {
+			continue
+		}
+		patch := client.MergeFrom(ns.DeepCopy())
+		delete(ns.Annotations, DeleteAfterAnnotation)
+		if err := cl.Patch(ctx, ns, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete the user namespaces whose deletion time is before now
+func reapNamespaces(ctx context.Context, cl client.Client, now time.Time) error {
+	namespaces, err := listUserNamespaces(ctx, cl)
+	if err != nil {
+		return err
+	}
+	for i := range namespaces {
+		ns := &namespaces[i]
+		value, found := ns.Annotations[DeleteAfterAnnotation]
+		if !found || ns.DeletionTimestamp != nil {
+			continue
+		}
+		deleteAfter, err := time.Parse(time.RFC3339, value)
+		if err != nil || deleteAfter.After(now) {
+			continue
+		}
+		if err := cl.Delete(ctx, ns); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/handlers/signup"
+	"github.com/konflux-ci/workspace-manager/pkg/policy"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	"github.com/konflux-ci/workspace-manager/pkg/username"
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	signup.Register("namespace", func(opts signup.Options) (signup.SignupBackend, error) {
+		if opts.Client == nil {
+			return nil, errors.New("the namespace signup backend requires a kubernetes client")
+		}
+		if opts.Namespace == "" {
+			return nil, errors.New("the namespace signup backend requires a namespace to store signups")
+		}
+		if opts.Policy == nil {
+			opts.Policy = policy.AllowAll{}
+		}
+		if opts.Usernames == nil {
+			opts.Usernames = username.NewGenerator(nil)
+		}
+		if opts.Tiers == nil {
+			opts.Tiers = tiers.Default()
+		}
+		if opts.OwnerRole == "" {
+			admin, _ := roles.Default().Get(roles.Admin)
+			opts.OwnerRole = admin.ClusterRole.Name
+		}
+		if opts.VerificationRequired && opts.Notifier == nil {
+			return nil, errors.New("the namespace signup backend requires a notifier to verify emails")
+		}
+		if opts.Verification.CodeExpiry == 0 {
+			opts.Verification.CodeExpiry = 10 * time.Minute
+		}
+		if opts.Verification.MaxAttempts == 0 {
+			opts.Verification.MaxAttempts = 3
+		}
+		if opts.Verification.DailyLimit == 0 {
+			opts.Verification.DailyLimit = 5
+		}
+		return &backend{
+			client:    opts.Client,
+			records:   &recordStore{client: opts.Client, namespace: opts.Namespace},
+			usernames: opts.Usernames,
+			opts:      opts,
+		}, nil
+	})
+}
+
+// backend provisions a namespace for every user that signs up, once
+// the signup is approved
+type backend struct {
+	client    client.Client
+	records   *recordStore
+	usernames *username.Generator
+	opts      signup.Options
+}
+
+// Record the signup of the calling user and provision its namespace if the
+// signup is approved
+func (b *backend) PostHandler(c echo.Context) error {
+	email := auth.Email(c)
+	if email == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	ctx := c.Request().Context()
+	record, err := b.records.get(ctx, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record == nil {
+		record, err = b.newRecord(c, email)
+		if err != nil {
+			return err
+		}
+	} else if record.CompliantUsername == "" {
+		// the record was created before usernames were assigned
+		if record.CompliantUsername, err = b.generateUsername(c, email); err != nil {
+			return err
+		}
+		if err := b.records.update(ctx, record); err != nil {
+			return updateError(c, err)
+		}
+	}
+
+	if record.DeactivatedAt != nil {
+		if !strings.EqualFold(record.DeactivatedBy, email) {
+			return echo.NewHTTPError(http.StatusForbidden, "the user was deactivated by an administrator")
+		}
+		// signing up again after leaving
+		return b.reactivate(c, record)
+	}
+	return b.proceed(c, record)
+}
+
+// Provision the namespace of the user unless its signup still waits for an
+// approval or for the verification of its email
+func (b *backend) proceed(c echo.Context, record *v1alpha1.SignupApproval) error {
+	if !b.verified(record) {
+		return c.String(http.StatusAccepted, "ok")
+	}
+	switch record.State {
+	case v1alpha1.ApprovalPending:
+		return c.String(http.StatusAccepted, "ok")
+	case v1alpha1.ApprovalRejected:
+		return echo.NewHTTPError(http.StatusForbidden, "the signup was rejected")
+	}
+	return b.provision(c, record)
+}
+
+// Create the signup record of a user, assigning it a username and approving
+// it when no administrator approval is needed. Users provisioned before
+// approvals or verifications were required are approved and verified.
+func (b *backend) newRecord(c echo.Context, email string) (*v1alpha1.SignupApproval, error) {
+	ctx := c.Request().Context()
+	compliantUsername, err := b.generateUsername(c, email)
+	if err != nil {
+		return nil, err
+	}
+	record := &v1alpha1.SignupApproval{
+		Email:             email,
+		CompliantUsername: compliantUsername,
+		State:             v1alpha1.ApprovalPending,
+	}
+	now := metav1.Now()
+	if b.opts.AutoApproved(email) {
+		record.State = v1alpha1.ApprovalApproved
+		record.DecidedAt = &now
+		record.Reason = "automatically approved"
+	}
+	if record.State != v1alpha1.ApprovalApproved || b.opts.VerificationRequired {
+		status, err := namespaceStatus(ctx, b.client, username.Namespace(compliantUsername), email)
+		if err != nil {
+			c.Logger().Error(err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		if status != nil {
+			if record.State != v1alpha1.ApprovalApproved {
+				record.State = v1alpha1.ApprovalApproved
+				record.DecidedAt = &now
+				record.Reason = "already provisioned"
+			}
+			record.VerifiedAt = &now
+		}
+	}
+	if err := b.records.create(ctx, record); err != nil {
+		c.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return record, nil
+}
+
+// Derive a username for the user that is neither assigned to another user
+// nor used by a namespace owned by someone else
+func (b *backend) generateUsername(c echo.Context, email string) (string, error) {
+	compliantUsername, err := b.usernames.Generate(
+		c.Request().Context(),
+		email,
+		func(ctx context.Context, name string) (bool, error) {
+			taken, err := b.records.usernameTaken(ctx, name, email)
+			if err != nil || taken {
+				return taken, err
+			}
+			return namespaceTaken(ctx, b.client, username.Namespace(name), email)
+		},
+	)
+	if err != nil {
+		c.Logger().Error(err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return compliantUsername, nil
+}
+
+func (b *backend) provision(c echo.Context, record *v1alpha1.SignupApproval) error {
+	if err := b.provisionNamespace(c, record); err != nil {
+		return err
+	}
+	return c.String(http.StatusOK, "ok")
+}
+
+// Provision the namespace of the user. The namespace may have been
+// provisioned for another user since the username was assigned, as happens
+// when users with the same compliant username sign up concurrently: the user
+// is then assigned a new username.
+func (b *backend) provisionNamespace(c echo.Context, record *v1alpha1.SignupApproval) error {
+	ctx := c.Request().Context()
+	provision := func() error {
+		return ProvisionNamespace(
+			ctx,
+			b.client,
+			username.Namespace(record.CompliantUsername),
+			record.Email,
+			b.opts.OwnerRole,
+			b.opts.Tiers.DefaultTier(),
+		)
+	}
+	err := provision()
+	if errors.Is(err, ErrNamespaceTaken) {
+		if record.CompliantUsername, err = b.generateUsername(c, record.Email); err != nil {
+			return err
+		}
+		if err := b.records.update(ctx, record); err != nil {
+			return updateError(c, err)
+		}
+		err = provision()
+	}
+	if errors.Is(err, ErrNamespaceTaken) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	} else if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return nil
+}
+
+// Report the approval state of the calling user signup and of its namespace
+func (b *backend) GetHandler(c echo.Context) error {
+	email := auth.Email(c)
+	if email == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	if decision := b.opts.Policy.Evaluate(email); !decision.Allowed {
+		return b.respond(c, &v1alpha1.Signup{
+			SignupStatus: v1alpha1.SignupStatus{
+				Reason:  v1alpha1.Banned,
+				Message: decision.Reason,
+			},
+		})
+	}
+	ctx := c.Request().Context()
+	record, err := b.records.get(ctx, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record != nil && record.DeactivatedAt != nil {
+		return b.respond(c, &v1alpha1.Signup{
+			CompliantUsername: record.CompliantUsername,
+			SignupStatus:      deactivatedStatus(record, b.opts.DeactivationGracePeriod),
+		})
+	}
+	if record != nil && !b.verified(record) {
+		return b.respond(c, &v1alpha1.Signup{
+			CompliantUsername: record.CompliantUsername,
+			SignupStatus:      verificationStatus(record),
+		})
+	}
+	if record != nil && record.State != v1alpha1.ApprovalApproved {
+		return b.respond(c, &v1alpha1.Signup{
+			CompliantUsername: record.CompliantUsername,
+			SignupStatus:      approvalStatus(record),
+		})
+	}
+
+	compliantUsername := b.usernames.Compliant(email)
+	if record != nil && record.CompliantUsername != "" {
+		compliantUsername = record.CompliantUsername
+	}
+	nsName := username.Namespace(compliantUsername)
+	status, err := namespaceStatus(ctx, b.client, nsName, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if status == nil {
+		if record == nil {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		// approved but not provisioned yet
+		status = &v1alpha1.SignupStatus{
+			Reason:  v1alpha1.Provisioning,
+			Message: fmt.Sprintf("namespace %s is not created yet", nsName),
+		}
+	}
+	if record != nil {
+		status.CreationTimestamp = &record.RequestedAt
+		conditions := []metav1.Condition{approvedCondition(record)}
+		if b.opts.VerificationRequired {
+			conditions = append(conditions, verifiedCondition(record))
+		}
+		status.Conditions = append(conditions, status.Conditions...)
+	}
+	resp := &v1alpha1.Signup{
+		CompliantUsername: compliantUsername,
+		SignupStatus:      *status,
+	}
+	if status.Ready {
+		resp.DefaultUserNamespace = nsName
+	}
+	return b.respond(c, resp)
+}
+
+// Report whether the signup of the user is approved and verified and the user
+// was neither deactivated nor banned by the policy
+func (b *backend) Active(ctx context.Context, email string) (bool, error) {
+	if decision := b.opts.Policy.Evaluate(email); !decision.Allowed {
+		return false, nil
+	}
+	record, err := b.records.get(ctx, email)
+	if err != nil || record == nil {
+		return false, err
+	}
+	return record.DeactivatedAt == nil && b.verified(record) && record.State == v1alpha1.ApprovalApproved, nil
+}
+
+// Complete the signup response of the calling user and send it
+func (b *backend) respond(c echo.Context, resp *v1alpha1.Signup) error {
+	b.opts.Complete(c, resp)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Build the status of a signup that is not approved
+func approvalStatus(record *v1alpha1.SignupApproval) v1alpha1.SignupStatus {
+	status := v1alpha1.SignupStatus{
+		Reason:            v1alpha1.PendingApproval,
+		Message:           "the signup is waiting for an administrator to approve it",
+		CreationTimestamp: &record.RequestedAt,
+		Conditions:        []metav1.Condition{approvedCondition(record)},
+	}
+	if record.State == v1alpha1.ApprovalRejected {
+		status.Reason = v1alpha1.Rejected
+		status.Message = "the signup was rejected"
+		if record.Reason != "" {
+			status.Message += ": " + record.Reason
+		}
+	}
+	return status
+}
+
+func approvedCondition(record *v1alpha1.SignupApproval) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               v1alpha1.SignupApproved,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PendingApproval",
+		Message:            record.Reason,
+		LastTransitionTime: record.RequestedAt,
+	}
+	if record.DecidedAt != nil {
+		condition.LastTransitionTime = *record.DecidedAt
+	}
+	switch record.State {
+	case v1alpha1.ApprovalApproved:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Approved"
+	case v1alpha1.ApprovalRejected:
+		condition.Reason = "Rejected"
+	}
+	return condition
+}
@@ -0,0 +1,208 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Label identifying the namespaces that belong to Konflux users
+	TypeLabel = "konflux.ci/type"
+	// Value of TypeLabel for namespaces that belong to Konflux users
+	UserType = "user"
+	// Annotation holding the email of the user a namespace was provisioned for
+	OwnerAnnotation = "konflux.ci/owner"
+	// Name of the RoleBinding granting the owner admin access to its namespace
+	OwnerBindingName = "konflux-owner"
+	// Name of the RoleBinding granting the owner the ClusterRole of the admin
+	// role of the workspaces
+	OwnerRoleBindingName = "konflux-owner-role"
+)
+
+// ErrNamespaceTaken is returned when the namespace for a user already
+// exists but was provisioned for someone else.
+var ErrNamespaceTaken = errors.New("namespace is owned by another user")
+
+// Check whether the namespace exists and belongs to another user than the
+// one with the given email
+func namespaceTaken(ctx context.Context, cl client.Client, name string, email string) (bool, error) {
+	ns := &core.Namespace{}
+	err := cl.Get(ctx, types.NamespacedName{Name: name}, ns)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !strings.EqualFold(ns.Annotations[OwnerAnnotation], email), nil
+}
+
+// ProvisionNamespace creates the user namespace, binds its owner to the admin
+// and ownerRole ClusterRoles and applies the templates of tier. Calling it again
+// for an already provisioned namespace is a no-op, its tier is kept.
+func ProvisionNamespace(
+	ctx context.Context, cl client.Client, name string, email string, ownerRole string, tier *tiers.Tier,
+) error {
+	ns := &core.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				TypeLabel: UserType,
+			},
+			Annotations: map[string]string{
+				OwnerAnnotation: email,
+			},
+		},
+	}
+	err := cl.Create(ctx, ns)
+	if apierrors.IsAlreadyExists(err) {
+		if err := cl.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+			return err
+		}
+		if !strings.EqualFold(ns.Annotations[OwnerAnnotation], email) {
+			return ErrNamespaceTaken
+		}
+	} else if err != nil {
+		return err
+	}
+	// the namespace is labelled with its tier once the tier is applied
+	_, tiered := ns.Labels[tiers.TierLabel]
+
+	if err := bindOwner(ctx, cl, name, email, ownerRole); err != nil {
+		return err
+	}
+	if tiered {
+		return nil
+	}
+	return tier.Apply(ctx, cl, name, email)
+}
+
+// Apply again the tier the namespace is labelled with, or the default tier of
+// the catalog when the namespace has none or one that is no longer defined,
+// restoring the objects of the tier removed since, such as the RoleBindings
+// of the owner removed on deactivation
+func reapplyTier(ctx context.Context, cl client.Client, name string, email string, catalog *tiers.Catalog) error {
+	ns := &core.Namespace{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		return err
+	}
+	tier, found := catalog.Get(ns.Labels[tiers.TierLabel])
+	if !found {
+		tier = catalog.DefaultTier()
+	}
+	return tier.Apply(ctx, cl, name, email)
+}
+
+// Bind the owner of the namespace to the admin ClusterRole of the cluster and
+// to the ownerRole ClusterRole. The existing bindings are kept as they are.
+func bindOwner(ctx context.Context, cl client.Client, namespace string, email string, ownerRole string) error {
+	bindings := []*rbacv1.RoleBinding{ownerBinding(namespace, OwnerBindingName, email, "admin")}
+	if ownerRole != "" && ownerRole != "admin" {
+		bindings = append(bindings, ownerBinding(namespace, OwnerRoleBindingName, email, ownerRole))
+	}
+	for _, binding := range bindings {
+		if err := cl.Create(ctx, binding); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// A RoleBinding granting the owner of the namespace the given ClusterRole
+func ownerBinding(namespace string, name string, email string, clusterRole string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:     rbacv1.UserKind,
+				Name:     email,
+				APIGroup: rbacv1.GroupName,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			Name:     clusterRole,
+			APIGroup: rbacv1.GroupName,
+		},
+	}
+}
+
+// Compute the signup status of the user from its namespace. A nil status is
+// returned if the user has no namespace.
+func namespaceStatus(ctx context.Context, cl client.Client, name string, email string) (*v1alpha1.SignupStatus, error) {
+	ns := &core.Namespace{}
+	err := cl.Get(ctx, types.NamespacedName{Name: name}, ns)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(ns.Annotations[OwnerAnnotation], email) {
+		return nil, nil
+	}
+
+	status := &v1alpha1.SignupStatus{
+		CreationTimestamp: &ns.CreationTimestamp,
+	}
+	if ns.DeletionTimestamp != nil {
+		status.Reason = v1alpha1.Deactivated
+		status.Message = fmt.Sprintf("namespace %s is being deleted", name)
+		setConditions(status, metav1.ConditionFalse, "Terminating", status.Message, *ns.DeletionTimestamp)
+		return status, nil
+	}
+	if ns.Status.Phase != core.NamespaceActive {
+		status.Reason = v1alpha1.Provisioning
+		status.Message = fmt.Sprintf("namespace %s is not active yet", name)
+		setConditions(status, metav1.ConditionFalse, "NamespaceNotActive", status.Message, ns.CreationTimestamp)
+		return status, nil
+	}
+
+	binding := &rbacv1.RoleBinding{}
+	err = cl.Get(ctx, types.NamespacedName{Namespace: name, Name: OwnerBindingName}, binding)
+	if apierrors.IsNotFound(err) {
+		status.Reason = v1alpha1.Provisioning
+		status.Message = fmt.Sprintf("granting %s access to namespace %s", email, name)
+		setConditions(status, metav1.ConditionFalse, "BindingMissing", status.Message, ns.CreationTimestamp)
+		return status, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	status.Ready = true
+	status.Reason = v1alpha1.SignedUp
+	status.Message = fmt.Sprintf("namespace %s is ready", name)
+	setConditions(status, metav1.ConditionTrue, "NamespaceProvisioned", status.Message, binding.CreationTimestamp)
+	return status, nil
+}
+
+// Report the provisioning state through the Provisioned and Ready conditions
+func setConditions(
+	status *v1alpha1.SignupStatus,
+	conditionStatus metav1.ConditionStatus,
+	reason string,
+	message string,
+	transitionTime metav1.Time,
+) {
+	for _, conditionType := range []string{v1alpha1.SignupProvisioned, v1alpha1.SignupReady} {
+		status.Conditions = append(status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: transitionTime,
+		})
+	}
+}
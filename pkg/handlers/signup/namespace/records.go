@@ -0,0 +1,166 @@
+package namespace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Label holding the approval state of a signup record
+	recordStateLabel = "konflux.ci/signup-state"
+	// Label holding the username assigned to the user of a signup record
+	recordUsernameLabel = "konflux.ci/compliant-username"
+)
+
+// Signup records are persisted as UserSignup resources so that the state of
+// the signups survives restarts and is shared between replicas
+type recordStore struct {
+	client    client.Client
+	namespace string
+}
+
+// Build the name of the UserSignup holding the record of a user. The email
+// is hashed since it can't be used as an object name.
+func recordName(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "signup-" + hex.EncodeToString(sum[:])[:32]
+}
+
+// Get the signup record of a user. A nil record is returned if the user
+// never signed up.
+func (s *recordStore) get(ctx context.Context, email string) (*v1alpha1.SignupApproval, error) {
+	us := &v1alpha1.UserSignup{}
+	err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: recordName(email)}, us)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return recordFromUserSignup(us), nil
+}
+
+// List the signup records in the given state
+func (s *recordStore) list(ctx context.Context, state v1alpha1.SignupApprovalState) ([]v1alpha1.SignupApproval, error) {
+	uss := &v1alpha1.UserSignupList{}
+	err := s.client.List(ctx, uss, client.InNamespace(s.namespace), client.MatchingLabels{recordStateLabel: state})
+	if err != nil {
+		return nil, err
+	}
+	records := []v1alpha1.SignupApproval{}
+	for i := range uss.Items {
+		records = append(records, *recordFromUserSignup(&uss.Items[i]))
+	}
+	return records, nil
+}
+
+// Check whether the username is assigned to another user than the one with
+// the given email
+func (s *recordStore) usernameTaken(ctx context.Context, name string, email string) (bool, error) {
+	uss := &v1alpha1.UserSignupList{}
+	err := s.client.List(ctx, uss, client.InNamespace(s.namespace), client.MatchingLabels{recordUsernameLabel: name})
+	if err != nil {
+		return false, err
+	}
+	for _, us := range uss.Items {
+		if !strings.EqualFold(us.Spec.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Create the record of a new signup
+func (s *recordStore) create(ctx context.Context, record *v1alpha1.SignupApproval) error {
+	us := &v1alpha1.UserSignup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      recordName(record.Email),
+			Namespace: s.namespace,
+		},
+	}
+	setUserSignup(us, record)
+	if err := s.client.Create(ctx, us); err != nil {
+		return err
+	}
+	record.ResourceVersion = us.ResourceVersion
+	return nil
+}
+
+// Persist the changes made to an existing record. A Conflict error is
+// returned when the record was changed since it was read.
+func (s *recordStore) update(ctx context.Context, record *v1alpha1.SignupApproval) error {
+	us := &v1alpha1.UserSignup{}
+	err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: recordName(record.Email)}, us)
+	if err != nil {
+		return err
+	}
+	setUserSignup(us, record)
+	us.ResourceVersion = record.ResourceVersion
+	if err := s.client.Update(ctx, us); err != nil {
+		return err
+	}
+	record.ResourceVersion = us.ResourceVersion
+	return nil
+}
+
+// Answer the failure to update a record, the changes made concurrently by
+// another request are reported as a conflict for the client to retry
+func updateError(c echo.Context, err error) error {
+	if apierrors.IsConflict(err) {
+		return echo.NewHTTPError(http.StatusConflict, "the signup was changed by another request, retry")
+	}
+	c.Logger().Error(err)
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
+
+func recordFromUserSignup(us *v1alpha1.UserSignup) *v1alpha1.SignupApproval {
+	return &v1alpha1.SignupApproval{
+		Email:             us.Spec.Email,
+		CompliantUsername: us.Spec.CompliantUsername,
+		State:             us.Spec.State,
+		RequestedAt:       us.CreationTimestamp,
+		DecidedAt:         us.Spec.DecidedAt,
+		DecidedBy:         us.Spec.DecidedBy,
+		Reason:            us.Spec.Reason,
+		DeactivatedAt:     us.Spec.DeactivatedAt,
+		DeactivatedBy:     us.Spec.DeactivatedBy,
+		VerifiedAt:        us.Spec.VerifiedAt,
+		Verification:      us.Spec.Verification,
+		ResourceVersion:   us.ResourceVersion,
+	}
+}
+
+// Copy the record into the UserSignup, the labels are kept in sync with the
+// spec so that the records can be filtered by state and username
+func setUserSignup(us *v1alpha1.UserSignup, record *v1alpha1.SignupApproval) {
+	us.Spec = v1alpha1.UserSignupSpec{
+		Email:             record.Email,
+		CompliantUsername: record.CompliantUsername,
+		State:             record.State,
+		DecidedAt:         record.DecidedAt,
+		DecidedBy:         record.DecidedBy,
+		Reason:            record.Reason,
+		DeactivatedAt:     record.DeactivatedAt,
+		DeactivatedBy:     record.DeactivatedBy,
+		VerifiedAt:        record.VerifiedAt,
+		Verification:      record.Verification,
+	}
+	if us.Labels == nil {
+		us.Labels = map[string]string{}
+	}
+	us.Labels[recordStateLabel] = record.State
+	if record.CompliantUsername != "" {
+		us.Labels[recordUsernameLabel] = record.CompliantUsername
+	} else {
+		delete(us.Labels, recordUsernameLabel)
+	}
+}
@@ -0,0 +1,67 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNamespaceBackend(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Namespace signup backend Suite")
+}
+
+var _ = Describe("Signup records", func() {
+	var records *recordStore
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		schema := runtime.NewScheme()
+		utilruntime.Must(v1alpha1.AddToScheme(schema))
+		records = &recordStore{client: fake.NewClientBuilder().WithScheme(schema).Build(), namespace: "signups"}
+		Expect(records.create(ctx, &v1alpha1.SignupApproval{
+			Email: "user@konflux.dev",
+			State: v1alpha1.ApprovalPending,
+		})).To(Succeed())
+	})
+
+	It("updates a record read before", func() {
+		record, err := records.get(ctx, "user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+		record.State = v1alpha1.ApprovalApproved
+		Expect(records.update(ctx, record)).To(Succeed())
+		// the record can be updated again after its update
+		record.Reason = "approved twice"
+		Expect(records.update(ctx, record)).To(Succeed())
+
+		record, err = records.get(ctx, "user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.State).To(Equal(v1alpha1.ApprovalApproved))
+		Expect(record.Reason).To(Equal("approved twice"))
+	})
+
+	It("rejects the update of a record changed since it was read", func() {
+		first, err := records.get(ctx, "user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := records.get(ctx, "user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+
+		first.State = v1alpha1.ApprovalApproved
+		Expect(records.update(ctx, first)).To(Succeed())
+		second.State = v1alpha1.ApprovalRejected
+		err = records.update(ctx, second)
+		Expect(apierrors.IsConflict(err)).To(BeTrue(), "unexpected error %v", err)
+
+		record, err := records.get(ctx, "user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.State).To(Equal(v1alpha1.ApprovalApproved))
+	})
+})
@@ -0,0 +1,190 @@
+package namespace
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Period over which the codes sent to a user are counted
+const verificationWindow = 24 * time.Hour
+
+// Send a verification code to the calling user
+func (b *backend) InitiateVerificationHandler(c echo.Context) error {
+	email := auth.Email(c)
+	if email == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	ctx := c.Request().Context()
+	record, err := b.records.get(ctx, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "the user has to sign up first")
+	}
+	if record.DeactivatedAt != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "the user is deactivated")
+	}
+	if record.VerifiedAt != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "the email is already verified")
+	}
+
+	now := time.Now()
+	limits := b.opts.Verification
+	verification := record.Verification
+	if verification == nil || now.Sub(verification.WindowStart.Time) >= verificationWindow {
+		verification = &v1alpha1.SignupVerification{WindowStart: metav1.NewTime(now)}
+	} else if retryAt := verification.SentAt.Add(limits.ResendInterval); now.Before(retryAt) {
+		return tooManyRequests(c, retryAt.Sub(now), "a verification code was sent recently")
+	} else if verification.Sent >= limits.DailyLimit {
+		retryAfter := verification.WindowStart.Add(verificationWindow).Sub(now)
+		return tooManyRequests(c, retryAfter, "too many verification codes were sent")
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	verification.CodeHash = hashCode(code)
+	verification.SentAt = metav1.NewTime(now)
+	verification.ExpiresAt = metav1.NewTime(now.Add(limits.CodeExpiry))
+	verification.Attempts = 0
+	verification.Sent++
+	record.Verification = verification
+	// the code is persisted before being sent so that every code the user
+	// receives can be verified
+	if err := b.records.update(ctx, record); err != nil {
+		return updateError(c, err)
+	}
+	body := fmt.Sprintf(
+		"Your Konflux verification code is %s\n\nIt expires in %s.\n",
+		code,
+		limits.CodeExpiry,
+	)
+	if err := b.opts.Notifier.Notify(ctx, email, "Konflux verification code", body); err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "the verification code could not be sent")
+	}
+	return c.String(http.StatusOK, "ok")
+}
+
+// Check the verification code given in the code path parameter and proceed
+// with the signup of the calling user once it matches
+func (b *backend) VerifyHandler(c echo.Context) error {
+	email := auth.Email(c)
+	if email == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	ctx := c.Request().Context()
+	record, err := b.records.get(ctx, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if record == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "the user has to sign up first")
+	}
+	if record.DeactivatedAt != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "the user is deactivated")
+	}
+	if record.VerifiedAt == nil {
+		verification := record.Verification
+		if verification == nil || verification.CodeHash == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "no verification code was requested")
+		}
+		if verification.Attempts >= b.opts.Verification.MaxAttempts {
+			return echo.NewHTTPError(http.StatusTooManyRequests, "too many wrong codes, request a new one")
+		}
+		if time.Now().After(verification.ExpiresAt.Time) {
+			return echo.NewHTTPError(http.StatusBadRequest, "the verification code expired, request a new one")
+		}
+		if subtle.ConstantTimeCompare([]byte(hashCode(c.Param("code"))), []byte(verification.CodeHash)) != 1 {
+			verification.Attempts++
+			if err := b.records.update(ctx, record); err != nil {
+				return updateError(c, err)
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "invalid verification code")
+		}
+		now := metav1.Now()
+		record.VerifiedAt = &now
+		record.Verification = nil
+		if err := b.records.update(ctx, record); err != nil {
+			return updateError(c, err)
+		}
+	}
+	return b.proceed(c, record)
+}
+
+// Report whether the signup can proceed as far as email verification is concerned
+func (b *backend) verified(record *v1alpha1.SignupApproval) bool {
+	return !b.opts.VerificationRequired || record.VerifiedAt != nil
+}
+
+// Build the status of a signup waiting for the user to verify its email
+func verificationStatus(record *v1alpha1.SignupApproval) v1alpha1.SignupStatus {
+	message := "a verification code has to be requested to verify the email"
+	if record.Verification != nil && record.Verification.CodeHash != "" {
+		message = fmt.Sprintf(
+			"a verification code was sent to %s, it expires on %s",
+			record.Email,
+			record.Verification.ExpiresAt.UTC().Format(time.RFC3339),
+		)
+	}
+	return v1alpha1.SignupStatus{
+		Reason:            v1alpha1.VerificationRequired,
+		Message:           message,
+		CreationTimestamp: &record.RequestedAt,
+		Conditions: []metav1.Condition{
+			approvedCondition(record),
+			{
+				Type:               v1alpha1.SignupVerified,
+				Status:             metav1.ConditionFalse,
+				Reason:             "VerificationRequired",
+				Message:            message,
+				LastTransitionTime: record.RequestedAt,
+			},
+		},
+	}
+}
+
+func verifiedCondition(record *v1alpha1.SignupApproval) metav1.Condition {
+	return metav1.Condition{
+		Type:               v1alpha1.SignupVerified,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Verified",
+		LastTransitionTime: *record.VerifiedAt,
+	}
+}
+
+func tooManyRequests(c echo.Context, retryAfter time.Duration, message string) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	return echo.NewHTTPError(http.StatusTooManyRequests, message)
+}
+
+// Generate a random 6 digits code
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+package signup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/notify"
+	"github.com/konflux-ci/workspace-manager/pkg/policy"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	"github.com/konflux-ci/workspace-manager/pkg/username"
+	"github.com/labstack/echo/v4"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SignupBackend serves the /api/v1/signup endpoint
+type SignupBackend interface {
+	// Handle POST /api/v1/signup
+	PostHandler(c echo.Context) error
+	// Handle GET /api/v1/signup
+	GetHandler(c echo.Context) error
+	// Report whether the user with the given email completed the signup and
+	// is allowed to use the service
+	Active(ctx context.Context, email string) (bool, error)
+}
+
+// ApprovalBackend is implemented by the signup backends able to hold
+// signups until an administrator approves them
+type ApprovalBackend interface {
+	// List the signups, by default the ones waiting for approval
+	ListHandler(c echo.Context) error
+	// Approve the signup of the user given in the email path parameter
+	ApproveHandler(c echo.Context) error
+	// Reject the signup of the user given in the email path parameter
+	RejectHandler(c echo.Context) error
+}
+
+// DeactivationBackend is implemented by the signup backends able to revoke
+// the access of users
+type DeactivationBackend interface {
+	// Deactivate the calling user
+	DeleteHandler(c echo.Context) error
+	// Deactivate the user given in the email path parameter
+	DeactivateHandler(c echo.Context) error
+	// Restore the access of the user given in the email path parameter
+	ReactivateHandler(c echo.Context) error
+	// Delete the namespaces retained for deactivated users once their grace
+	// period expired. Runs every interval until ctx is done.
+	ReapNamespaces(ctx context.Context, interval time.Duration, onError func(error))
+}
+
+// VerificationBackend is implemented by the signup backends able to make
+// users verify their email before their signup proceeds
+type VerificationBackend interface {
+	// Send a verification code to the calling user
+	InitiateVerificationHandler(c echo.Context) error
+	// Check the verification code given in the code path parameter
+	VerifyHandler(c echo.Context) error
+}
+
+// VerificationOptions configures the verification codes sent to the users
+type VerificationOptions struct {
+	// How long a code can be used
+	CodeExpiry time.Duration
+	// Number of wrong codes accepted before the user has to request a new one
+	MaxAttempts int
+	// Minimum time between two codes sent to a user
+	ResendInterval time.Duration
+	// Maximum number of codes sent to a user in a day
+	DailyLimit int
+}
+
+// ClusterInfo holds the URLs of the cluster reported to the signed up users
+type ClusterInfo struct {
+	ConsoleURL      string
+	APIEndpoint     string
+	CheDashboardURL string
+}
+
+// Options holds the dependencies and settings available to the signup backends
+type Options struct {
+	Client client.Client
+	// Namespace in which the backend persists its state
+	Namespace string
+	// Hold the signups until an administrator approves them
+	ApprovalRequired bool
+	// Email domains whose users are approved without waiting for an administrator
+	AutoApprovedDomains []string
+	// Policy deciding which users are allowed to sign up
+	Policy policy.Evaluator
+	// Generator of the usernames from which the user namespaces are named
+	Usernames *username.Generator
+	// How long the namespaces owned by deactivated users are retained
+	// before being deleted
+	DeactivationGracePeriod time.Duration
+	// Hold the signups until the users verify their email
+	VerificationRequired bool
+	// Delivers the verification codes to the users
+	Notifier     notify.Notifier
+	Verification VerificationOptions
+	// URLs of the cluster reported to the users once their signup is ready
+	Cluster ClusterInfo
+	// Tiers of the namespaces provisioned for the users, the new namespaces
+	// get the default tier
+	Tiers *tiers.Catalog
+	// Name of the ClusterRole the users are bound to in the namespaces
+	// provisioned for them besides the admin ClusterRole of the cluster, the
+	// one of the admin role
+	OwnerRole string
+}
+
+// AutoApproved reports whether the signup of the user with the given email
+// skips the approval queue
+func (o *Options) AutoApproved(email string) bool {
+	if !o.ApprovalRequired {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	for _, d := range o.AutoApprovedDomains {
+		if strings.EqualFold(d, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete fills the signup response with the profile of the calling user
+// and, once the signup is ready, with the URLs of the cluster
+func (o *Options) Complete(c echo.Context, resp *v1alpha1.Signup) {
+	header := c.Request().Header
+	resp.Username = auth.Username(c)
+	resp.GivenName = header.Get(auth.GivenNameHeader)
+	resp.FamilyName = header.Get(auth.FamilyNameHeader)
+	resp.Company = header.Get(auth.CompanyHeader)
+	if resp.SignupStatus.Ready {
+		resp.ConsoleURL = o.Cluster.ConsoleURL
+		resp.APIEndpoint = o.Cluster.APIEndpoint
+		resp.CheDashboardURL = o.Cluster.CheDashboardURL
+	}
+}
+
+// Factory creates a SignupBackend from the given options
+type Factory func(opts Options) (SignupBackend, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Factory{}
+)
+
+// Register makes a signup backend available under the given name.
+// It is meant to be called from the init function of the backend package
+// and panics if the name is already taken.
+func Register(name string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, found := backends[name]; found {
+		panic(fmt.Sprintf("signup backend %q is already registered", name))
+	}
+	backends[name] = factory
+}
+
+// Backends returns the sorted names of the registered signup backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the signup backend registered under the given name
+func New(name string, opts Options) (SignupBackend, error) {
+	backendsMu.RLock()
+	factory, found := backends[name]
+	backendsMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf(
+			"unknown signup backend %q, available backends: %s",
+			name,
+			strings.Join(Backends(), ", "),
+		)
+	}
+	return factory(opts)
+}
@@ -0,0 +1,87 @@
+package signup_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/handlers/signup"
+	_ "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/dummy"
+	_ "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+)
+
+func TestSignup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Signup backends Suite")
+}
+
+var _ = Describe("Signup backend registry", func() {
+	It("lists the registered backends", func() {
+		Expect(signup.Backends()).To(ContainElements("dummy", "namespace"))
+	})
+
+	It("creates the backend selected by name", func() {
+		backend, err := signup.New("dummy", signup.Options{})
+		Expect(err).NotTo(HaveOccurred())
+
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/signup", nil), rec)
+		Expect(backend.GetHandler(c)).To(Succeed())
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(strings.TrimSpace(rec.Body.String())).To(Equal(`{"status":{"ready":true,"reason":"SignedUp"}}`))
+	})
+
+	It("completes the response with the user profile and the cluster URLs", func() {
+		backend, err := signup.New("dummy", signup.Options{
+			Cluster: signup.ClusterInfo{
+				ConsoleURL:      "https://console.konflux.dev",
+				APIEndpoint:     "https://api.konflux.dev:6443",
+				CheDashboardURL: "https://che.konflux.dev",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/signup", nil)
+		req.Header.Set("X-Email", "user@konflux.dev")
+		req.Header.Set("X-Given-Name", "Jane")
+		req.Header.Set("X-Family-Name", "Doe")
+		req.Header.Set("X-Company", "Konflux")
+		Expect(backend.GetHandler(e.NewContext(req, rec))).To(Succeed())
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		resp := &v1alpha1.Signup{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), resp)).To(Succeed())
+		Expect(resp.Username).To(Equal("user@konflux.dev"))
+		Expect(resp.GivenName).To(Equal("Jane"))
+		Expect(resp.FamilyName).To(Equal("Doe"))
+		Expect(resp.Company).To(Equal("Konflux"))
+		Expect(resp.ConsoleURL).To(Equal("https://console.konflux.dev"))
+		Expect(resp.APIEndpoint).To(Equal("https://api.konflux.dev:6443"))
+		Expect(resp.CheDashboardURL).To(Equal("https://che.konflux.dev"))
+	})
+
+	It("fails for an unknown backend", func() {
+		_, err := signup.New("unknown", signup.Options{})
+		Expect(err).To(MatchError(ContainSubstring(`unknown signup backend "unknown"`)))
+	})
+
+	It("fails when a backend misses its dependencies", func() {
+		_, err := signup.New("namespace", signup.Options{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses to register the same name twice", func() {
+		Expect(func() {
+			signup.Register("dummy", func(signup.Options) (signup.SignupBackend, error) { return nil, nil })
+		}).To(Panic())
+	})
+})
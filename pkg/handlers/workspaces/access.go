@@ -0,0 +1,324 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	authorizationv1Client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accesscache"
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	signupnamespace "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+	"github.com/konflux-ci/workspace-manager/pkg/rbac"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+// Time after which the changes of the RoleBindings are expected to be seen by
+// the authorizer of the API server
+const RBACPropagationDelay = time.Second
+
+// namespacesWithAccess returns the namespaces, among the given ones, the
+// calling user has access to as workspaces
+type namespacesWithAccess func(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error)
+
+// accessCheck reports whether the user is allowed to perform check in namespace
+type accessCheck func(ctx context.Context, user string, namespace string, check accessrules.Check) (bool, error)
+
+// Find the owner of a workspace namespace, from its owner annotation or else
+// from the RoleBinding granting admin access to the namespace, through the
+// ClusterRole of the admin role or the admin ClusterRole of the cluster. An
+// empty owner is returned when none is found.
+func (h *Handlers) workspaceOwner(ns core.Namespace) (string, error) {
+	if owner := ns.Annotations[signupnamespace.OwnerAnnotation]; owner != "" {
+		return owner, nil
+	}
+	bindings, err := h.opts.RoleBindings.RoleBindings(ns.Name).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	// prefer the binding created when provisioning the namespace, then the
+	// oldest admin binding
+	sort.SliceStable(bindings, func(i, j int) bool {
+		a, b := bindings[i], bindings[j]
+		if (a.Name == signupnamespace.OwnerBindingName) != (b.Name == signupnamespace.OwnerBindingName) {
+			return a.Name == signupnamespace.OwnerBindingName
+		}
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	})
+	ownerRole := OwnerClusterRole(h.opts.Roles)
+	for _, binding := range bindings {
+		if binding.RoleRef.Kind != "ClusterRole" ||
+			(binding.RoleRef.Name != ownerRole && binding.RoleRef.Name != "admin") {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			if subject.Kind == rbacv1.UserKind {
+				return subject.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// Find the most privileged role the user holds in the namespace, empty if
+// the user holds none. The role the user is bound to in the namespace is
+// taken from the RoleBindings, only the more privileged ones are checked.
+func (h *Handlers) workspaceRole(ctx context.Context, user string, namespace string) (string, error) {
+	held, err := h.boundRole(user, namespace)
+	if err != nil {
+		return "", err
+	}
+	return h.opts.Roles.ResolveFrom(held, func(check accessrules.Check) (bool, error) {
+		return h.accessCheck(ctx, user, namespace, check)
+	})
+}
+
+// Find the most privileged role whose ClusterRole the user is bound to in the
+// namespace, empty if the user is bound to none
+func (h *Handlers) boundRole(user string, namespace string) (string, error) {
+	bindings, err := h.opts.RoleBindings.RoleBindings(namespace).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	held := -1
+	for _, binding := range bindings {
+		if binding.RoleRef.Kind != "ClusterRole" {
+			continue
+		}
+		for i, role := range h.opts.Roles {
+			if i <= held || role.ClusterRole.Name != binding.RoleRef.Name {
+				continue
+			}
+			for _, subject := range binding.Subjects {
+				if subject.Kind == rbacv1.UserKind && subject.Name == user {
+					held = i
+				}
+			}
+		}
+	}
+	if held < 0 {
+		return "", nil
+	}
+	return h.opts.Roles[held].Name, nil
+}
+
+// Get all the namespace in which the calling user is allowed to perform enough actions
+// to allow workspace access. The namespaces are checked concurrently by up to
+// AccessCheckWorkers workers and are returned in the order they were given.
+func (h *Handlers) getNamespacesWithAccess(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error) {
+	user := auth.Email(c)
+	if user == "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	ctx := c.Request().Context()
+	allowed := make([]bool, len(allNamespaces))
+	err := forEachConcurrently(ctx, h.opts.AccessCheckWorkers, len(allNamespaces), func(i int) {
+		allowed[i] = h.hasWorkspaceAccess(c, user, allNamespaces[i].Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var allowedNs []core.Namespace
+	for i, ns := range allNamespaces {
+		if allowed[i] {
+			allowedNs = append(allowedNs, ns)
+		}
+	}
+	return allowedNs, nil
+}
+
+// Call fn for each index below n, concurrently on up to workers workers. The
+// remaining indexes are skipped once ctx is done, in which case the error of
+// ctx is returned.
+func forEachConcurrently(ctx context.Context, workers int, n int, fn func(i int)) error {
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	// stop handing out indexes once the context is done
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Check whether the user is allowed to perform enough actions in the namespace
+// to allow workspace access
+func (h *Handlers) hasWorkspaceAccess(c echo.Context, user string, namespace string) bool {
+	ctx := c.Request().Context()
+	allowed, err := h.opts.AccessRules.Evaluate(func(check accessrules.Check) (bool, error) {
+		return h.sarAccessCheck(ctx, user, namespace, check)
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			c.Logger().Error(err)
+		}
+		return false
+	}
+	return allowed
+}
+
+// Check the access of the user with a SubjectAccessReview, or from the cached
+// decisions
+func (h *Handlers) sarAccessCheck(ctx context.Context, user string, namespace string, check accessrules.Check) (bool, error) {
+	return h.cachedAccessCheck(ctx, user, namespace, check.Group, check.Resource, check.Verb)
+}
+
+// Build an accessCheck evaluating the RBAC rules locally with authorizer
+func localAccessCheck(authorizer *rbac.Authorizer) accessCheck {
+	return func(_ context.Context, user string, namespace string, check accessrules.Check) (bool, error) {
+		return authorizer.Allowed(user, authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      check.Verb,
+			Group:     check.Group,
+			Resource:  check.Resource,
+		})
+	}
+}
+
+// Build a namespacesWithAccess evaluating the RBAC rules locally with
+// authorizer instead of sending SubjectAccessReviews
+func (h *Handlers) localNamespacesWithAccess(authorizer *rbac.Authorizer) namespacesWithAccess {
+	accessCheck := localAccessCheck(authorizer)
+	return func(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error) {
+		user := auth.Email(c)
+		if user == "" {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized)
+		}
+		ctx := c.Request().Context()
+		var allowedNs []core.Namespace
+		for _, ns := range allNamespaces {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			allowed, err := h.opts.AccessRules.Evaluate(func(check accessrules.Check) (bool, error) {
+				return accessCheck(ctx, user, ns.Name, check)
+			})
+			if err != nil {
+				c.Logger().Error(err)
+				continue
+			}
+			if allowed {
+				allowedNs = append(allowedNs, ns)
+			}
+		}
+		return allowedNs, nil
+	}
+}
+
+// NewAccessDecisionCache creates an access decision cache invalidated when the
+// Roles or RoleBindings of a namespace change, or when the ClusterRoles or
+// ClusterRoleBindings do. The informers of factory are started.
+func NewAccessDecisionCache(
+	ctx context.Context, factory informers.SharedInformerFactory, opts accesscache.Options,
+) (*accesscache.Cache, error) {
+	decisions := accesscache.New(opts)
+	synced := decisions.InvalidateOnChange(factory)
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil, errors.New("failed to sync the RBAC caches")
+	}
+	return decisions, nil
+}
+
+// Run the access check through the access decision cache when it is enabled.
+// The SubjectAccessReviews are sent without the groups of the user.
+func (h *Handlers) cachedAccessCheck(
+	ctx context.Context,
+	user string,
+	namespace string,
+	resourceGroup string,
+	resource string,
+	verb string,
+) (bool, error) {
+	authCl := h.clientset.AuthorizationV1()
+	if h.opts.AccessDecisions == nil {
+		return runAccessCheck(ctx, authCl, user, namespace, resourceGroup, resource, verb)
+	}
+	key := accesscache.NewKey(user, namespace, resourceGroup, resource, verb)
+	return h.opts.AccessDecisions.Fetch(key, func() (bool, error) {
+		return runAccessCheck(ctx, authCl, user, namespace, resourceGroup, resource, verb)
+	})
+}
+
+// check if a user can perform a specific verb on a specific resource in namespace
+func runAccessCheck(
+	ctx context.Context,
+	authCl authorizationv1Client.AuthorizationV1Interface,
+	user string,
+	namespace string,
+	resourceGroup string,
+	resource string,
+	verb string,
+) (bool, error) {
+	sar := &authorizationv1.LocalSubjectAccessReview{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+		},
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User: user,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     resourceGroup,
+				Resource:  resource,
+			},
+		},
+	}
+	response, err := authCl.LocalSubjectAccessReviews(namespace).Create(
+		ctx, sar, metav1.CreateOptions{},
+	)
+	if err != nil {
+		return false, err
+	}
+	if response.Status.Allowed {
+		return true, nil
+	}
+	return false, nil
+}
+
+// check if a user holds the admin role in namespace, as required for
+// managing the workspace. The decision is never taken from the cache.
+func (h *Handlers) runAdminAccessCheck(ctx context.Context, user string, namespace string) (bool, error) {
+	admin, found := h.opts.Roles.Get(roles.Admin)
+	if !found {
+		return false, fmt.Errorf("the %s role is not defined", roles.Admin)
+	}
+	authCl := h.clientset.AuthorizationV1()
+	return admin.Rules.Evaluate(func(check accessrules.Check) (bool, error) {
+		return runAccessCheck(ctx, authCl, user, namespace, check.Group, check.Resource, check.Verb)
+	})
+}
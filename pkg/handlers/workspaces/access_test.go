@@ -0,0 +1,373 @@
+package workspaces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	authorizationv1Client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accesscache"
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/rbac"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+type NamespaceRoleBinding struct {
+	Namespace   string
+	Role        string
+	RoleBinding string
+}
+
+var _ = Describe("TestRunAccessCheck", func() {
+	var authCl authorizationv1Client.AuthorizationV1Interface
+
+	BeforeEach(func() {
+		// Set up Kubernetes client
+		cfg, err := config.GetConfig()
+		Expect(err).NotTo(HaveOccurred(), "Unexpected error getting Kubernetes config")
+		clientset, err := kubernetes.NewForConfig(cfg)
+		Expect(err).NotTo(HaveOccurred(), "Unexpected error creating Kubernetes clientset")
+		authCl = clientset.AuthorizationV1()
+	})
+
+	Context("When a user has access to the resource", func() {
+		It("should return true for a user with 'create' permission on test-tenant", func() {
+			user := "user3@konflux.dev"
+			namespace := "test-tenant"
+			resource := "applications"
+			verb := "create"
+			expectedResult := true
+			_, err := utils.CreateNamespace(k8sClient, namespace)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", namespace, err))
+			utils.CreateRole(k8sClient, "test-tenant", "namespace-access", []string{"create", "list", "watch", "delete"})
+			utils.CreateRoleBinding(k8sClient, "namespace-access-user-binding", "test-tenant", user, "namespace-access")
+			boolresult, err := runAccessCheck(context.Background(), authCl, user, namespace, "appstudio.redhat.com", resource, verb)
+			Expect(boolresult).To(Equal(expectedResult))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing RunAccessCheck")
+		})
+	})
+
+	Context("When a user does not have any permissions on the namespace", func() {
+		It("should return false for a user without access to test-tenant-2", func() {
+			user := "user4@konflux.dev"
+			namespace := "test-tenant-2"
+			resource := "applications"
+			verb := "create"
+			expectedResult := false
+			_, err := utils.CreateNamespace(k8sClient, namespace)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", namespace, err))
+
+			utils.CreateRole(k8sClient, "test-tenant-2", "namespace-access-2", []string{"create", "list", "watch", "delete"})
+			utils.CreateRoleBinding(k8sClient, "namespace-access-user-binding-3", "test-tenant-2", user, "namespace-access-2")
+			boolresult, err := runAccessCheck(context.Background(), authCl, "user3@konflux.dev", namespace, "appstudio.redhat.com", resource, verb)
+			Expect(boolresult).To(Equal(expectedResult))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing RunAccessCheck")
+		})
+	})
+
+	Context("When a user lacks the specific action permission on the namespace", func() {
+		It("should return false for a user without 'patch' permission on test-tenant-1", func() {
+			user := "user5@konflux.dev"
+			namespace := "test-tenant-1"
+			resource := "applications"
+			verb := "patch"
+			expectedResult := false
+			_, err := utils.CreateNamespace(k8sClient, namespace)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", namespace, err))
+			utils.CreateRole(k8sClient, "test-tenant-1", "namespace-access", []string{"create", "list", "watch", "delete"})
+			utils.CreateRoleBinding(k8sClient, "namespace-access-user-binding", "test-tenant-1", user, "namespace-access")
+			boolresult, err := runAccessCheck(context.Background(), authCl, user, namespace, "appstudio.redhat.com", resource, verb)
+			Expect(boolresult).To(Equal(expectedResult))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing RunAccessCheck")
+		})
+	})
+})
+var _ = Describe("TestGetNamespacesWithAccess", func() {
+	var (
+		allNamespaces, actualNs, expectedNs []core.Namespace
+		err                                 error
+		mappings                            []NamespaceRoleBinding
+		e                                   *echo.Echo
+		c                                   echo.Context
+		h                                   *Handlers
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		h = newTestHandlers(Options{})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c = e.NewContext(req, rec)
+		c.Request().Header.Set("X-Email", "user@konflux.dev")
+	})
+
+	Context("When all the namespaces have the necessary permissions", func() {
+		mappings = []NamespaceRoleBinding{
+			{
+				Namespace:   "ns-test-tenant-1",
+				Role:        "ns-namespace-access-1",
+				RoleBinding: "ns-namespace-access-user-binding-1",
+			},
+			{
+				Namespace:   "ns-test-tenant-2",
+				Role:        "ns-namespace-access-2",
+				RoleBinding: "ns-namespace-access-user-binding-2",
+			},
+		}
+		BeforeEach(func() {
+			for _, name := range mappings {
+				ns, err := utils.CreateNamespace(k8sClient, name.Namespace)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name.Namespace))
+				allNamespaces = append(allNamespaces, ns)
+				utils.CreateRole(k8sClient, name.Namespace, name.Role, []string{"create", "list", "watch", "delete"})
+				utils.CreateRoleBinding(k8sClient, name.RoleBinding, name.Namespace, "user@konflux.dev", name.Role)
+			}
+		})
+		It("returns all namespaces in the list", func() {
+			actualNs, err = h.getNamespacesWithAccess(c, allNamespaces)
+			Expect(actualNs).To(Equal(allNamespaces))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
+		})
+		AfterEach(func() {
+			allNamespaces = nil
+		})
+	})
+
+	Context("When none of the namspaces have necessary permissions", func() {
+		var name = "ns-test-tenant-3"
+		BeforeEach(func() {
+			ns3, err := utils.CreateNamespace(k8sClient, name)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name))
+			allNamespaces = []core.Namespace{ns3}
+		})
+		It("doesn't return any namespace", func() {
+			actualNs, err = h.getNamespacesWithAccess(c, allNamespaces)
+			Expect(actualNs).To(BeEmpty())
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
+		})
+		AfterEach(func() {
+			allNamespaces = nil
+		})
+	})
+
+	Context("When namspace ns-test-tenant-5 doesn't have necessary permissions", func() {
+		BeforeEach(func() {
+			var names = []string{"ns-test-tenant-5", "ns-test-tenant-6"}
+			for _, name := range names {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name))
+				allNamespaces = append(allNamespaces, ns)
+				expectedNs = []core.Namespace{ns}
+			}
+			utils.CreateRole(k8sClient, "ns-test-tenant-6", "ns-namespace-access-6", []string{"create", "list", "watch", "delete"})
+			utils.CreateRoleBinding(k8sClient, "ns-namespace-access-user-binding-6", "ns-test-tenant-6", "user@konflux.dev", "ns-namespace-access-6")
+		})
+		It("only returns ns-test-tenant-6 namespace", func() {
+			actualNs, err = h.getNamespacesWithAccess(c, allNamespaces)
+			Expect(actualNs).To(Equal(expectedNs))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
+		})
+		AfterEach(func() {
+			allNamespaces = nil
+		})
+	})
+
+	Context("When there are more namespaces than access check workers", func() {
+		BeforeEach(func() {
+			h.opts.AccessCheckWorkers = 2
+			expectedNs = nil
+			for i := 1; i <= 7; i++ {
+				name := fmt.Sprintf("ns-test-tenant-pool-%d", i)
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s", name))
+				allNamespaces = append(allNamespaces, ns)
+				if i%2 == 1 {
+					utils.CreateRole(k8sClient, name, "ns-namespace-access-pool", []string{"list", "watch"})
+					utils.CreateRoleBinding(k8sClient, "ns-namespace-access-pool-binding", name, "user@konflux.dev", "ns-namespace-access-pool")
+					expectedNs = append(expectedNs, ns)
+				}
+			}
+		})
+		It("returns the allowed namespaces in the given order", func() {
+			actualNs, err = h.getNamespacesWithAccess(c, allNamespaces)
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
+			Expect(actualNs).To(Equal(expectedNs))
+		})
+		AfterEach(func() {
+			allNamespaces = nil
+		})
+	})
+
+	Context("When the access decisions are cached", func() {
+		BeforeEach(func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			factory := informers.NewSharedInformerFactory(k8sClientset, 0)
+			var err error
+			h.opts.AccessDecisions, err = NewAccessDecisionCache(
+				ctx, factory, accesscache.Options{TTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 100},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			ns, err := utils.CreateNamespace(k8sClient, "ns-test-tenant-cached")
+			Expect(err).NotTo(HaveOccurred(), "Error while creating the namespace ns-test-tenant-cached")
+			allNamespaces = []core.Namespace{ns}
+		})
+		It("sees the access granted after a denied decision was cached", func() {
+			actualNs, err = h.getNamespacesWithAccess(c, allNamespaces)
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetNamespacesWithAccess")
+			Expect(actualNs).To(BeEmpty())
+
+			utils.CreateRole(k8sClient, "ns-test-tenant-cached", "ns-namespace-access-cached", []string{"list", "watch"})
+			utils.CreateRoleBinding(k8sClient, "ns-namespace-access-cached-binding", "ns-test-tenant-cached", "user@konflux.dev", "ns-namespace-access-cached")
+			Eventually(func() ([]core.Namespace, error) {
+				return h.getNamespacesWithAccess(c, allNamespaces)
+			}).Should(Equal(allNamespaces))
+		})
+		AfterEach(func() {
+			allNamespaces = nil
+		})
+	})
+
+	Context("When the request goes away", func() {
+		BeforeEach(func() {
+			ns, err := utils.CreateNamespace(k8sClient, "ns-test-tenant-cancelled")
+			Expect(err).NotTo(HaveOccurred(), "Error while creating the namespace ns-test-tenant-cancelled")
+			allNamespaces = []core.Namespace{ns}
+		})
+		It("stops checking the namespaces", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			actualNs, err = h.getNamespacesWithAccess(c, allNamespaces)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(actualNs).To(BeEmpty())
+		})
+		AfterEach(func() {
+			allNamespaces = nil
+		})
+	})
+})
+var _ = Describe("TestLocalNamespacesWithAccess", func() {
+	var (
+		e               *echo.Echo
+		h               *Handlers
+		localAccess     namespacesWithAccess
+		namespaces      []core.Namespace
+		requestForEmail func(email string) echo.Context
+	)
+	userA := "rbac-user-a@konflux.dev"
+	userB := "rbac-user-b@konflux.dev"
+
+	BeforeEach(func() {
+		e = echo.New()
+		h = newTestHandlers(Options{})
+
+		factory := informers.NewSharedInformerFactory(k8sClientset, 0)
+		authorizer, err := rbac.NewAuthorizer(factory)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		factory.Start(ctx.Done())
+		Expect(authorizer.WaitForCacheSync(ctx)).To(BeTrue())
+		localAccess = h.localNamespacesWithAccess(authorizer)
+
+		requestForEmail = func(email string) echo.Context {
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			c.Request().Header.Set("X-Email", email)
+			return c
+		}
+	})
+
+	Context("When the users are granted access in different ways", Ordered, func() {
+		BeforeAll(func() {
+			namespaces = nil
+			for i := 1; i <= 4; i++ {
+				ns, err := utils.CreateNamespace(k8sClient, fmt.Sprintf("rbac-test-tenant-%d", i))
+				Expect(err).NotTo(HaveOccurred())
+				namespaces = append(namespaces, ns)
+			}
+			// full access through a role
+			utils.CreateRole(k8sClient, "rbac-test-tenant-1", "rbac-access", []string{"list", "watch"})
+			utils.CreateRoleBinding(k8sClient, "rbac-access-binding", "rbac-test-tenant-1", userA, "rbac-access")
+			// partial access through a role
+			utils.CreateRole(k8sClient, "rbac-test-tenant-2", "rbac-access", []string{"list"})
+			utils.CreateRoleBinding(k8sClient, "rbac-access-binding", "rbac-test-tenant-2", userA, "rbac-access")
+			// access through a cluster role with wildcards
+			clusterRole := &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "rbac-test-appstudio-reader"},
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups: []string{"appstudio.redhat.com"},
+					Resources: []string{"*"},
+					Verbs:     []string{"get", "list", "watch"},
+				}},
+			}
+			Expect(k8sClient.Create(context.Background(), clusterRole)).To(Succeed())
+			binding := &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "rbac-access-binding", Namespace: "rbac-test-tenant-3"},
+				Subjects:   []rbacv1.Subject{{Kind: "User", Name: userA, APIGroup: rbacv1.GroupName}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: clusterRole.Name, APIGroup: rbacv1.GroupName},
+			}
+			Expect(k8sClient.Create(context.Background(), binding)).To(Succeed())
+			// access granted to a group only
+			groupBinding := &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "rbac-group-binding", Namespace: "rbac-test-tenant-4"},
+				Subjects:   []rbacv1.Subject{{Kind: "Group", Name: userA, APIGroup: rbacv1.GroupName}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: clusterRole.Name, APIGroup: rbacv1.GroupName},
+			}
+			Expect(k8sClient.Create(context.Background(), groupBinding)).To(Succeed())
+			// cluster wide access
+			clusterBinding := &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "rbac-test-cluster-reader"},
+				Subjects:   []rbacv1.Subject{{Kind: "User", Name: userB, APIGroup: rbacv1.GroupName}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: clusterRole.Name, APIGroup: rbacv1.GroupName},
+			}
+			Expect(k8sClient.Create(context.Background(), clusterBinding)).To(Succeed())
+		})
+
+		It("only returns the namespaces with full access", func() {
+			Eventually(func() ([]core.Namespace, error) {
+				return localAccess(requestForEmail(userA), namespaces)
+			}).Should(Equal([]core.Namespace{namespaces[0], namespaces[2]}))
+			Eventually(func() ([]core.Namespace, error) {
+				return localAccess(requestForEmail(userB), namespaces)
+			}).Should(Equal(namespaces))
+		})
+
+		It("agrees with the SubjectAccessReviews", func() {
+			for _, user := range []string{userA, userB, "rbac-user-c@konflux.dev"} {
+				expected, err := h.getNamespacesWithAccess(requestForEmail(user), namespaces)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(func() ([]core.Namespace, error) {
+					return localAccess(requestForEmail(user), namespaces)
+				}).Should(Equal(expected), "mismatch for "+user)
+			}
+		})
+
+		It("applies the configured access rules", func() {
+			rules, err := accessrules.Parse([]byte(
+				"match: any\nrules:\n- group: appstudio.redhat.com\n  resources: [applications]\n  verbs: [list]",
+			))
+			Expect(err).NotTo(HaveOccurred())
+			h.opts.AccessRules = rules
+
+			expected := []core.Namespace{namespaces[0], namespaces[1], namespaces[2]}
+			Eventually(func() ([]core.Namespace, error) {
+				return localAccess(requestForEmail(userA), namespaces)
+			}).Should(Equal(expected))
+			Expect(h.getNamespacesWithAccess(requestForEmail(userA), namespaces)).To(Equal(expected))
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Label selector of the user namespaces
+const userNamespaceSelector = "konflux.ci/type=user"
+
+// errNamespaceCacheNotSynced is returned while the user namespaces are
+// still being loaded
+var errNamespaceCacheNotSynced = errors.New("the namespace cache has not synced yet")
+
+// NamespaceCache holds the user namespaces, kept up to date by an informer
+type NamespaceCache struct {
+	informer cache.SharedIndexInformer
+	lister   corelisters.NamespaceLister
+	synced   cache.InformerSynced
+}
+
+// StartNamespaceCache starts an informer caching the user namespaces. The
+// cache is filled in the background, until then HasSynced returns false.
+func StartNamespaceCache(ctx context.Context, clientset kubernetes.Interface) *NamespaceCache {
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		clientset,
+		0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = userNamespaceSelector
+		}),
+	)
+	informer := factory.Core().V1().Namespaces()
+	// the managed fields are never used and take most of the memory
+	utilruntime.Must(informer.Informer().SetTransform(func(obj interface{}) (interface{}, error) {
+		if ns, ok := obj.(*core.Namespace); ok {
+			ns.ManagedFields = nil
+		}
+		return obj, nil
+	}))
+	namespaces := &NamespaceCache{
+		informer: informer.Informer(),
+		lister:   informer.Lister(),
+		synced:   informer.Informer().HasSynced,
+	}
+	factory.Start(ctx.Done())
+	return namespaces
+}
+
+// HasSynced reports whether the user namespaces are loaded
+func (n *NamespaceCache) HasSynced() bool {
+	return n.synced()
+}
+
+// Gets all user namespaces that satisfy the provided requirement, sorted by name
+func getUserNamespaces(namespaces *NamespaceCache, nameReq labels.Requirement) ([]core.Namespace, error) {
+	if !namespaces.synced() {
+		return nil, errNamespaceCacheNotSynced
+	}
+	req, _ := labels.NewRequirement("konflux.ci/type", selection.In, []string{"user"})
+	selector := labels.NewSelector().Add(*req)
+	selector = selector.Add(nameReq)
+	cached, err := namespaces.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	userNamespaces := make([]core.Namespace, 0, len(cached))
+	for _, ns := range cached {
+		userNamespaces = append(userNamespaces, *ns)
+	}
+	sort.Slice(userNamespaces, func(i, j int) bool {
+		return userNamespaces[i].Name < userNamespaces[j].Name
+	})
+	return userNamespaces, nil
+}
+
+// Gets the user namespaces of the workspace: the namespace named after it,
+// unless it belongs to another workspace, and the namespaces labelled with
+// its name
+func getWorkspaceNamespaces(namespaces *NamespaceCache, ws string) ([]core.Namespace, error) {
+	nameReq, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.In, []string{ws})
+	if err != nil {
+		// not a valid label value, so neither a namespace name nor a workspace label
+		return nil, nil
+	}
+	workspaceReq, _ := labels.NewRequirement(workspaceLabel, selection.In, []string{ws})
+	named, err := getUserNamespaces(namespaces, *nameReq)
+	if err != nil {
+		return nil, err
+	}
+	labelled, err := getUserNamespaces(namespaces, *workspaceReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var wsNamespaces []core.Namespace
+	for _, ns := range named {
+		if workspaceName(ns) == ws {
+			wsNamespaces = append(wsNamespaces, ns)
+		}
+	}
+	for _, ns := range labelled {
+		if ns.Name != ws {
+			wsNamespaces = append(wsNamespaces, ns)
+		}
+	}
+	return wsNamespaces, nil
+}
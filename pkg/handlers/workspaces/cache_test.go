@@ -0,0 +1,125 @@
+package workspaces
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+var _ = Describe("GetUserNamespaces", func() {
+	var namespaces *NamespaceCache
+	var namespaceNames func(labels.Requirement) ([]string, error)
+	var createdNamespaces []string
+
+	BeforeEach(func() {
+		namespaces = startTestNamespaceCache()
+
+		// the cache is eventually consistent, the names are polled
+		namespaceNames = func(req labels.Requirement) ([]string, error) {
+			userNamespaces, err := getUserNamespaces(namespaces, req)
+			var names []string
+			for _, ns := range userNamespaces {
+				names = append(names, ns.Name)
+			}
+			return names, err
+		}
+	})
+
+	// checks if all created namespaces are in the returned list
+	Context("When querying for all user namespaces using Exists", func() {
+		It("Should return all created namespaces", func() {
+			namesToCreate := []string{"test-ns-1", "test-ns-2", "test-ns-3"}
+			for _, name := range namesToCreate {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating the namespace %s", name))
+				createdNamespaces = append(createdNamespaces, ns.Name)
+			}
+
+			req, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.Exists, []string{})
+			Expect(err).NotTo(HaveOccurred(), "Error creating label requirement")
+
+			Eventually(func() ([]string, error) {
+				return namespaceNames(*req)
+			}).Should(ContainElements(createdNamespaces))
+		})
+
+		AfterEach(func() {
+			createdNamespaces = nil
+		})
+	})
+	// checks if specific namespaces are in the returned list
+	Context("When querying for specific namespaces using In", func() {
+		It("Should return only the specified namespaces", func() {
+			for _, name := range []string{"in-test-1", "in-test-2", "not-in-test"} {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating the namespace %s", name))
+				createdNamespaces = append(createdNamespaces, ns.Name)
+			}
+
+			req, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.In, []string{"in-test-1", "in-test-2"})
+			Expect(err).NotTo(HaveOccurred(), "Error creating label requirement")
+
+			Eventually(func() ([]string, error) {
+				return namespaceNames(*req)
+			}).Should(Equal([]string{"in-test-1", "in-test-2"}))
+		})
+	})
+
+	Context("When querying for the namespaces of a workspace", func() {
+		It("Should return the namespace named after it and the labelled ones", func() {
+			for name, wsLabel := range map[string]string{
+				"ws-group":         "",
+				"ws-group-managed": "ws-group",
+				"ws-group-other":   "ws-group-elsewhere",
+			} {
+				ns := &core.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"konflux.ci/type": "user"},
+				}}
+				if wsLabel != "" {
+					ns.Labels["konflux.ci/workspace"] = wsLabel
+				}
+				Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
+			}
+
+			Eventually(func() ([]string, error) {
+				wsNamespaces, err := getWorkspaceNamespaces(namespaces, "ws-group")
+				var names []string
+				for _, ns := range wsNamespaces {
+					names = append(names, ns.Name)
+				}
+				return names, err
+			}).Should(Equal([]string{"ws-group", "ws-group-managed"}))
+			Expect(getWorkspaceNamespaces(namespaces, "Not a label value!")).To(BeEmpty())
+		})
+	})
+
+	Context("When querying for namespaces using NotIn", func() {
+		It("Should return namespaces not in the specified list", func() {
+			for _, name := range []string{"ts-keep-1", "ts-keep-2", "ts-exclude-1", "ts-exclude-2"} {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating the namespace %s", name))
+				createdNamespaces = append(createdNamespaces, ns.Name)
+			}
+
+			req, err := labels.NewRequirement("kubernetes.io/metadata.name", selection.NotIn, []string{"ts-exclude-1", "ts-exclude-2"})
+			Expect(err).NotTo(HaveOccurred(), "Error creating label requirement")
+
+			Eventually(func() ([]string, error) {
+				return namespaceNames(*req)
+			}).Should(And(
+				ContainElements("ts-keep-1", "ts-keep-2"),
+				Not(ContainElement("ts-exclude-1")),
+				Not(ContainElement("ts-exclude-2")),
+			))
+		})
+	})
+})
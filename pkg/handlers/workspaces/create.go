@@ -0,0 +1,150 @@
+package workspaces
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	signupnamespace "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	"github.com/konflux-ci/workspace-manager/pkg/username"
+)
+
+// Prefixes of the namespaces reserved to the cluster
+var reservedNamespacePrefixes = []string{"kube-", "openshift-"}
+
+// Check that the name can be used for a new workspace
+func validateWorkspaceName(name string) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid workspace name %q: %s", name, strings.Join(errs, ", "))
+	}
+	if name == "default" {
+		return fmt.Errorf("invalid workspace name %q: reserved name", name)
+	}
+	if username.IsNamespace(name) {
+		return fmt.Errorf("invalid workspace name %q: the names of the user namespaces are reserved", name)
+	}
+	for _, prefix := range reservedNamespacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("invalid workspace name %q: the %s prefix is reserved", name, prefix)
+		}
+	}
+	return nil
+}
+
+// Count the user namespaces owned by the user, besides the default namespace
+// provisioned on signup
+func countOwnedNamespaces(namespaces *NamespaceCache, email string) (int, error) {
+	nameReq, _ := labels.NewRequirement("kubernetes.io/metadata.name", selection.Exists, []string{})
+	userNamespaces, err := getUserNamespaces(namespaces, *nameReq)
+	if err != nil {
+		return 0, err
+	}
+	owned := 0
+	for _, ns := range userNamespaces {
+		if strings.EqualFold(ns.Annotations[signupnamespace.OwnerAnnotation], email) && !username.IsNamespace(ns.Name) {
+			owned++
+		}
+	}
+	return owned, nil
+}
+
+// Create a workspace, made of a single namespace, for the calling user who
+// becomes its owner and admin. The signup of the user has to be complete. The users may own up to WorkspaceLimit
+// namespaces besides the one provisioned on signup.
+func (h *Handlers) CreateHandler(c echo.Context) error {
+	email := auth.Email(c)
+	if email == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	requested := crt.Workspace{}
+	if err := c.Bind(&requested); err != nil {
+		return err
+	}
+	name := requested.Name
+	if err := validateWorkspaceName(name); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	existing, err := getWorkspaceNamespaces(h.namespaces, name)
+	if errors.Is(err, errNamespaceCacheNotSynced) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	} else if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("workspace %s already exists", name))
+	}
+	ctx := c.Request().Context()
+	active, err := h.opts.Signups.Active(ctx, email)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	if !active {
+		return echo.NewHTTPError(http.StatusForbidden, "the signup of the user is not complete")
+	}
+	if limit := h.opts.WorkspaceLimit; limit > 0 {
+		owned, err := countOwnedNamespaces(h.namespaces, email)
+		if err != nil {
+			return err
+		}
+		if owned >= limit {
+			return echo.NewHTTPError(
+				http.StatusForbidden, fmt.Sprintf("the limit of %d workspaces per user is reached", limit),
+			)
+		}
+	}
+
+	ns := &core.Namespace{}
+	err = h.client.Get(ctx, client.ObjectKey{Name: name}, ns)
+	if err == nil {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("namespace %s already exists", name))
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+	tier := h.opts.Tiers.DefaultTier()
+	err = signupnamespace.ProvisionNamespace(ctx, h.client, name, email, OwnerClusterRole(h.opts.Roles), tier)
+	if errors.Is(err, signupnamespace.ErrNamespaceTaken) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("namespace %s already exists", name))
+	} else if err != nil {
+		return err
+	}
+	if err := h.recordActivity(ctx, []core.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: name}}}); err != nil {
+		return err
+	}
+
+	// the namespace cache may not know the new workspace yet
+	role, err := h.workspaceRole(ctx, email, name)
+	if err != nil {
+		return err
+	}
+	ws := crt.Workspace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Workspace",
+			APIVersion: crt.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{tiers.TierLabel: tier.Name},
+		},
+		Status: crt.WorkspaceStatus{
+			Namespaces: []crt.SpaceNamespace{{Name: name, Type: defaultNamespaceType}},
+			Owner:      email,
+			Role:       role,
+		},
+	}
+	return c.JSON(http.StatusCreated, &ws)
+}
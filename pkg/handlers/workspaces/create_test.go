@@ -0,0 +1,191 @@
+package workspaces
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/handlers/signup"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+var _ = Describe("Workspace creation", Ordered, func() {
+	var server *httptest.Server
+	user := "create-user@konflux.dev"
+	deleter := "create-deleter@konflux.dev"
+
+	BeforeAll(func() {
+		_, err := utils.CreateNamespace(k8sClient, "create-test-taken")
+		Expect(err).NotTo(HaveOccurred())
+		// the namespace provisioned on signup doesn't count toward the limit
+		tenant := &core.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "create-user-tenant",
+			Labels:      map[string]string{"konflux.ci/type": "user"},
+			Annotations: map[string]string{"konflux.ci/owner": user},
+		}}
+		Expect(k8sClient.Create(context.Background(), tenant)).To(Succeed())
+
+		namespaces := startTestNamespaceCache()
+
+		h := newTestHandlers(Options{Namespaces: namespaces, WorkspaceLimit: 2})
+
+		e := echo.New()
+		e.POST("/workspaces", h.CreateHandler)
+		e.DELETE("/workspaces/:ws", h.DeleteHandler)
+		server = httptest.NewServer(e)
+		DeferCleanup(server.Close)
+	})
+
+	// Call the workspaces endpoint as the given user
+	call := func(method string, path string, email string, body string) *HTTPResponse {
+		return performJSONCall(method, server.URL+"/workspaces"+path, email, body)
+	}
+
+	// Request the creation of a workspace as the given user
+	create := func(email string, body string) *HTTPResponse {
+		return call(http.MethodPost, "", email, body)
+	}
+
+	It("creates the workspace of the caller", func() {
+		start := time.Now().Truncate(time.Second)
+		resp := create(user, `{"metadata":{"name":"create-test-1"}}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(resp.Body).To(MatchJSON(`{"kind":"Workspace","apiVersion":"toolchain.dev.openshift.com/v1alpha1",` +
+			`"metadata":{"name":"create-test-1","creationTimestamp":null,"labels":{"konflux.ci/tier":"base"}},` +
+			`"status":{"namespaces":[{"name":"create-test-1","type":"default"}],` +
+			`"owner":"create-user@konflux.dev","role":"admin"}}`))
+
+		ns := &core.Namespace{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "create-test-1"}, ns)).To(Succeed())
+		Expect(ns.Labels).To(HaveKeyWithValue("konflux.ci/type", "user"))
+		Expect(ns.Labels).To(HaveKeyWithValue("konflux.ci/tier", "base"))
+		Expect(ns.Annotations).To(HaveKeyWithValue("konflux.ci/owner", user))
+		Expect(lastActivityOf("create-test-1")).To(BeTemporally(">=", start))
+		binding := &rbacv1.RoleBinding{}
+		key := client.ObjectKey{Namespace: "create-test-1", Name: "konflux-owner"}
+		Expect(k8sClient.Get(context.Background(), key, binding)).To(Succeed())
+		Expect(binding.RoleRef.Name).To(Equal("admin"))
+		Expect(binding.Subjects).To(ConsistOf(HaveField("Name", user)))
+		key = client.ObjectKey{Namespace: "create-test-1", Name: "konflux-owner-role"}
+		Expect(k8sClient.Get(context.Background(), key, binding)).To(Succeed())
+		Expect(binding.RoleRef.Name).To(Equal("konflux-admin-user-actions"))
+		Expect(binding.Subjects).To(ConsistOf(HaveField("Name", user)))
+	})
+
+	It("lets the caller delete the workspace", func() {
+		Expect(create(deleter, `{"metadata":{"name":"create-test-deleted"}}`).StatusCode).To(Equal(http.StatusCreated))
+		// the namespace cache sees the workspace eventually
+		Eventually(func() int {
+			return call(http.MethodDelete, "/create-test-deleted", deleter, "").StatusCode
+		}).Should(Equal(http.StatusAccepted))
+		ns := &core.Namespace{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "create-test-deleted"}, ns)).To(Succeed())
+		Expect(ns.DeletionTimestamp).NotTo(BeNil())
+	})
+
+	It("rejects an existing workspace", func() {
+		Expect(create(user, `{"metadata":{"name":"create-test-taken"}}`).StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("enforces the limit of workspaces per user", func() {
+		Expect(create(user, `{"metadata":{"name":"create-test-2"}}`).StatusCode).To(Equal(http.StatusCreated))
+		Eventually(func() int {
+			return create(user, `{"metadata":{"name":"create-test-3"}}`).StatusCode
+		}).Should(Equal(http.StatusForbidden))
+		Expect(create("other-create-user@konflux.dev", `{"metadata":{"name":"create-test-3"}}`).StatusCode).
+			To(Equal(http.StatusCreated))
+	})
+
+	It("requires the caller's email", func() {
+		Expect(create("", `{"metadata":{"name":"create-test-anonymous"}}`).StatusCode).
+			To(Equal(http.StatusUnauthorized))
+	})
+
+	DescribeTable("rejecting invalid requests",
+		func(body string) {
+			Expect(create(user, body).StatusCode).To(Equal(http.StatusBadRequest))
+		},
+		Entry("without name", `{"metadata":{}}`),
+		Entry("with an invalid name", `{"metadata":{"name":"Create_Test"}}`),
+		Entry("with a too long name", `{"metadata":{"name":"`+strings.Repeat("a", 64)+`"}}`),
+		Entry("with a reserved prefix", `{"metadata":{"name":"kube-create-test"}}`),
+		Entry("with a reserved name", `{"metadata":{"name":"default"}}`),
+		Entry("with the name of a user namespace", `{"metadata":{"name":"create-test-tenant"}}`),
+		Entry("with a malformed body", `{"metadata":`),
+	)
+})
+
+var _ = Describe("Workspace creation by users whose signup is not complete", Ordered, func() {
+	var server *httptest.Server
+
+	BeforeAll(func() {
+		_, err := utils.CreateNamespace(k8sClient, "create-test-signups")
+		Expect(err).NotTo(HaveOccurred())
+		backend, err := signup.New("namespace", signup.Options{
+			Client:              k8sClient,
+			Namespace:           "create-test-signups",
+			ApprovalRequired:    true,
+			AutoApprovedDomains: []string{"konflux.dev"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		h := newTestHandlers(Options{Namespaces: startTestNamespaceCache(), Signups: backend})
+
+		e := echo.New()
+		e.POST("/signup", backend.PostHandler)
+		e.POST("/signups/:email/reject", backend.(signup.ApprovalBackend).RejectHandler)
+		e.POST("/signups/:email/deactivate", backend.(signup.DeactivationBackend).DeactivateHandler)
+		e.POST("/workspaces", h.CreateHandler)
+		server = httptest.NewServer(e)
+		DeferCleanup(server.Close)
+	})
+
+	// Sign the given user up
+	signUp := func(email string) int {
+		return performJSONCall(http.MethodPost, server.URL+"/signup", email, "").StatusCode
+	}
+
+	// Request the creation of a workspace as the given user
+	create := func(email string, name string) int {
+		body := `{"metadata":{"name":"` + name + `"}}`
+		return performJSONCall(http.MethodPost, server.URL+"/workspaces", email, body).StatusCode
+	}
+
+	It("lets the users whose signup is complete create workspaces", func() {
+		Expect(signUp("signed-up@konflux.dev")).To(Equal(http.StatusOK))
+		Expect(create("signed-up@konflux.dev", "create-test-signed-up")).To(Equal(http.StatusCreated))
+	})
+
+	It("rejects the users who didn't sign up", func() {
+		Expect(create("not-signed-up@konflux.dev", "create-test-not-signed-up")).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects the users whose signup is pending", func() {
+		Expect(signUp("pending@external.dev")).To(Equal(http.StatusAccepted))
+		Expect(create("pending@external.dev", "create-test-pending")).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects the users whose signup was rejected", func() {
+		Expect(signUp("rejected@external.dev")).To(Equal(http.StatusAccepted))
+		resp := performJSONCall(http.MethodPost, server.URL+"/signups/rejected@external.dev/reject", "admin", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(create("rejected@external.dev", "create-test-rejected")).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects the deactivated users", func() {
+		Expect(signUp("deactivated@konflux.dev")).To(Equal(http.StatusOK))
+		resp := performJSONCall(http.MethodPost, server.URL+"/signups/deactivated@konflux.dev/deactivate", "admin", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(create("deactivated@konflux.dev", "create-test-deactivated")).To(Equal(http.StatusForbidden))
+	})
+})
@@ -0,0 +1,88 @@
+package workspaces
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+// Find the workspace of the calling user and check that the user holds the
+// admin role in each of its namespaces, action describing what requires it.
+// The namespaces of the workspace that are not terminating are returned.
+func (h *Handlers) adminWorkspaceNamespaces(c echo.Context, action string) (*crt.Workspace, []core.Namespace, error) {
+	user := auth.Email(c)
+	if user == "" {
+		return nil, nil, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	name := c.Param("ws")
+	ws, err := h.getWorkspace(c, name)
+	if errors.Is(err, errNamespaceCacheNotSynced) {
+		return nil, nil, echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if ws == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound)
+	}
+	wsNamespaces, err := getWorkspaceNamespaces(h.namespaces, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := c.Request().Context()
+	var active []core.Namespace
+	for _, ns := range wsNamespaces {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		allowed, err := h.runAdminAccessCheck(ctx, user, ns.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !allowed {
+			return nil, nil, echo.NewHTTPError(
+				http.StatusForbidden, fmt.Sprintf("%s workspace %s requires the %s role", action, name, roles.Admin),
+			)
+		}
+		active = append(active, ns)
+	}
+	return ws, active, nil
+}
+
+// Delete the namespaces of a workspace the calling user is admin of. The
+// workspace is returned as terminating, it keeps being listed as such until
+// its namespaces are gone.
+func (h *Handlers) DeleteHandler(c echo.Context) error {
+	ws, wsNamespaces, err := h.adminWorkspaceNamespaces(c, "deleting")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	for _, ns := range wsNamespaces {
+		err := h.client.Delete(ctx, &ns)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		c.Logger().Infof("deleting namespace %s of workspace %s for %s", ns.Name, ws.Name, auth.Email(c))
+	}
+
+	if ws.DeletionTimestamp == nil {
+		ns := &core.Namespace{}
+		err = h.client.Get(ctx, client.ObjectKey{Name: ws.Status.Namespaces[0].Name}, ns)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		ws.DeletionTimestamp = ns.DeletionTimestamp
+	}
+	return c.JSON(http.StatusAccepted, ws)
+}
@@ -0,0 +1,103 @@
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+var _ = Describe("Workspace deletion", Ordered, func() {
+	var server *httptest.Server
+	owner := "delete-owner@konflux.dev"
+	viewer := "delete-viewer@konflux.dev"
+
+	BeforeAll(func() {
+		ns := &core.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "delete-test",
+			Labels:      map[string]string{"konflux.ci/type": "user"},
+			Annotations: map[string]string{"konflux.ci/owner": owner},
+		}}
+		Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
+		utils.CreateRole(k8sClient, "delete-test", "delete-test-viewer", []string{"list", "watch"})
+		utils.CreateRoleBinding(k8sClient, "delete-test-owner-viewer", "delete-test", owner, "delete-test-viewer")
+		utils.CreateRoleBinding(k8sClient, "delete-test-viewer", "delete-test", viewer, "delete-test-viewer")
+		utils.BindClusterRole(k8sClient, "delete-test-admin", "delete-test", owner, "konflux-admin-user-actions")
+
+		namespaces := startTestNamespaceCache()
+
+		h := newTestHandlers(Options{Namespaces: namespaces})
+
+		e := echo.New()
+		e.GET("/workspaces/:ws", h.GetHandler)
+		e.DELETE("/workspaces/:ws", h.DeleteHandler)
+		server = httptest.NewServer(e)
+		DeferCleanup(server.Close)
+	})
+
+	// Call the workspace endpoint as the given user, the workspace returned
+	// is decoded into ws
+	call := func(method string, email string, ws *crt.Workspace) int {
+		req, err := http.NewRequest(method, server.URL+"/workspaces/delete-test", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("X-Email", email)
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		if ws != nil {
+			Expect(json.NewDecoder(resp.Body).Decode(ws)).To(Succeed())
+		}
+		return resp.StatusCode
+	}
+
+	It("hides the workspace from the users without access", func() {
+		Expect(call(http.MethodDelete, "nobody@konflux.dev", nil)).To(Equal(http.StatusNotFound))
+	})
+
+	It("requires the admin role", func() {
+		Expect(call(http.MethodDelete, viewer, nil)).To(Equal(http.StatusForbidden))
+		ns := &core.Namespace{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "delete-test"}, ns)).To(Succeed())
+		Expect(ns.DeletionTimestamp).To(BeNil())
+	})
+
+	It("deletes the namespace of the workspace", func() {
+		ws := crt.Workspace{}
+		Expect(call(http.MethodDelete, owner, &ws)).To(Equal(http.StatusAccepted))
+		Expect(ws.Name).To(Equal("delete-test"))
+		Expect(ws.DeletionTimestamp).NotTo(BeNil())
+		ns := &core.Namespace{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "delete-test"}, ns)).To(Succeed())
+		Expect(ns.DeletionTimestamp).NotTo(BeNil())
+	})
+
+	It("reports the workspace as terminating", func() {
+		Eventually(func() *metav1.Time {
+			ws := crt.Workspace{}
+			Expect(call(http.MethodGet, viewer, &ws)).To(Equal(http.StatusOK))
+			return ws.DeletionTimestamp
+		}).ShouldNot(BeNil())
+	})
+
+	It("keeps reporting the workspace to its owner once its RoleBindings are gone", func() {
+		for _, name := range []string{"delete-test-owner-viewer", "delete-test-admin"} {
+			binding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "delete-test"}}
+			Expect(k8sClient.Delete(context.Background(), binding)).To(Succeed())
+		}
+		ws := crt.Workspace{}
+		Expect(call(http.MethodGet, owner, &ws)).To(Equal(http.StatusOK))
+		Expect(ws.DeletionTimestamp).NotTo(BeNil())
+		Expect(ws.Status.Owner).To(Equal(owner))
+	})
+})
@@ -0,0 +1,186 @@
+package workspaces
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+const (
+	// Label marking the RoleBindings managed by the service
+	managedByLabel = "app.kubernetes.io/managed-by"
+	// Value of managedByLabel for the RoleBindings managed by the service
+	managedBy = "workspace-manager"
+	// Label of the member RoleBindings holding the role granted to the member
+	memberRoleLabel = "konflux.ci/member-role"
+)
+
+// workspaceMember is a user granted a role in a workspace
+type workspaceMember struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// workspaceMemberList lists the members of a workspace
+type workspaceMemberList struct {
+	Items []workspaceMember `json:"items"`
+}
+
+// Build the name of the RoleBinding granting a role to a member. The email
+// is hashed since it can't be used as an object name.
+func memberBindingName(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "konflux-member-" + hex.EncodeToString(sum[:])[:32]
+}
+
+// Build the RoleBinding granting role to the member in namespace, through
+// the ClusterRole of the role
+func memberBinding(namespace string, email string, role roles.Role) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      memberBindingName(email),
+			Namespace: namespace,
+			Labels: map[string]string{
+				managedByLabel:  managedBy,
+				memberRoleLabel: role.Name,
+			},
+		},
+		Subjects: []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: email}},
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			APIGroup: rbacv1.GroupName,
+			Name:     role.ClusterRole.Name,
+		},
+	}
+}
+
+// List the members of a workspace the calling user is admin of, from the
+// RoleBindings managed by the service in its namespaces, sorted by email
+func (h *Handlers) ListMembersHandler(c echo.Context) error {
+	_, wsNamespaces, err := h.adminWorkspaceNamespaces(c, "listing the members of")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	found := map[string]bool{}
+	members := []workspaceMember{}
+	for _, ns := range wsNamespaces {
+		bindings := &rbacv1.RoleBindingList{}
+		err := h.client.List(ctx, bindings, client.InNamespace(ns.Name), client.MatchingLabels{managedByLabel: managedBy})
+		if err != nil {
+			return err
+		}
+		for _, binding := range bindings.Items {
+			role, ok := binding.Labels[memberRoleLabel]
+			if !ok || len(binding.Subjects) != 1 || found[binding.Subjects[0].Name] {
+				continue
+			}
+			found[binding.Subjects[0].Name] = true
+			members = append(members, workspaceMember{Email: binding.Subjects[0].Name, Role: role})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Email < members[j].Email
+	})
+	return c.JSON(http.StatusOK, &workspaceMemberList{Items: members})
+}
+
+// Grant a role to a user in all the namespaces of a workspace the calling
+// user is admin of. The role of an existing member is replaced.
+func (h *Handlers) AddMemberHandler(c echo.Context) error {
+	member := workspaceMember{}
+	if err := c.Bind(&member); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(member.Email); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid email %q", member.Email))
+	}
+	role, found := h.opts.Roles.Get(member.Role)
+	if !found {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown role %q", member.Role))
+	}
+	ws, wsNamespaces, err := h.adminWorkspaceNamespaces(c, "adding members to")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	status := http.StatusOK
+	for _, ns := range wsNamespaces {
+		binding := memberBinding(ns.Name, member.Email, role)
+		existing := &rbacv1.RoleBinding{}
+		err := h.client.Get(ctx, client.ObjectKeyFromObject(binding), existing)
+		switch {
+		case apierrors.IsNotFound(err):
+			status = http.StatusCreated
+		case err != nil:
+			return err
+		case existing.Labels[memberRoleLabel] == role.Name && existing.RoleRef.Name == binding.RoleRef.Name:
+			continue
+		default:
+			// the role of a RoleBinding can't be changed
+			if err := h.client.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		if err := h.client.Create(ctx, binding); err != nil {
+			return err
+		}
+	}
+	if err := h.recordActivity(ctx, wsNamespaces); err != nil {
+		return err
+	}
+	c.Logger().Infof("granted %s to %s in workspace %s for %s", member.Role, member.Email, ws.Name, auth.Email(c))
+	return c.JSON(status, &member)
+}
+
+// Revoke the role granted to a member in all the namespaces of a workspace
+// the calling user is admin of
+func (h *Handlers) RemoveMemberHandler(c echo.Context) error {
+	ws, wsNamespaces, err := h.adminWorkspaceNamespaces(c, "removing members from")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	email := c.Param("email")
+	removed := false
+	for _, ns := range wsNamespaces {
+		binding := &rbacv1.RoleBinding{}
+		err := h.client.Get(ctx, client.ObjectKey{Namespace: ns.Name, Name: memberBindingName(email)}, binding)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if binding.Labels[managedByLabel] != managedBy {
+			continue
+		}
+		if err := h.client.Delete(ctx, binding); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s is not a member of workspace %s", email, ws.Name))
+	}
+	if err := h.recordActivity(ctx, wsNamespaces); err != nil {
+		return err
+	}
+	c.Logger().Infof("removed %s from workspace %s for %s", email, ws.Name, auth.Email(c))
+	return c.NoContent(http.StatusNoContent)
+}
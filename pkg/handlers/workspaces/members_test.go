@@ -0,0 +1,118 @@
+package workspaces
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+var _ = Describe("Workspace members", Ordered, func() {
+	var server *httptest.Server
+	admin := "members-admin@konflux.dev"
+	member := "members-member@konflux.dev"
+
+	BeforeAll(func() {
+		_, err := utils.CreateNamespace(k8sClient, "members-test")
+		Expect(err).NotTo(HaveOccurred())
+		utils.CreateRole(k8sClient, "members-test", "members-test-viewer", []string{"list", "watch"})
+		utils.CreateRoleBinding(k8sClient, "members-test-admin-viewer", "members-test", admin, "members-test-viewer")
+		utils.CreateRoleBinding(k8sClient, "members-test-viewer", "members-test", "members-viewer@konflux.dev",
+			"members-test-viewer")
+		utils.BindClusterRole(k8sClient, "members-test-admin", "members-test", admin, "konflux-admin-user-actions")
+
+		namespaces := startTestNamespaceCache()
+
+		h := newTestHandlers(Options{Namespaces: namespaces})
+
+		e := echo.New()
+		e.GET("/workspaces/:ws/members", h.ListMembersHandler)
+		e.POST("/workspaces/:ws/members", h.AddMemberHandler)
+		e.DELETE("/workspaces/:ws/members/:email", h.RemoveMemberHandler)
+		server = httptest.NewServer(e)
+		DeferCleanup(server.Close)
+	})
+
+	// Call the members endpoint of the workspace as the given user
+	call := func(method string, path string, email string, body string) *HTTPResponse {
+		return performJSONCall(method, server.URL+"/workspaces/members-test/members"+path, email, body)
+	}
+
+	memberBindingOf := func(email string) (*rbacv1.RoleBinding, error) {
+		binding := &rbacv1.RoleBinding{}
+		key := client.ObjectKey{Namespace: "members-test", Name: memberBindingName(email)}
+		return binding, k8sClient.Get(context.Background(), key, binding)
+	}
+
+	It("requires the admin role", func() {
+		Expect(call(http.MethodGet, "", "members-viewer@konflux.dev", "").StatusCode).To(Equal(http.StatusForbidden))
+		Expect(call(http.MethodGet, "", "nobody@konflux.dev", "").StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	DescribeTable("rejecting invalid members",
+		func(body string) {
+			Expect(call(http.MethodPost, "", admin, body).StatusCode).To(Equal(http.StatusBadRequest))
+		},
+		Entry("without email", `{"role":"viewer"}`),
+		Entry("with an invalid email", `{"email":"members-member","role":"viewer"}`),
+		Entry("with an unknown role", `{"email":"members-member@konflux.dev","role":"owner"}`),
+	)
+
+	It("grants a role to a new member", func() {
+		start := time.Now().Truncate(time.Second)
+		resp := call(http.MethodPost, "", admin, `{"email":"members-member@konflux.dev","role":"contributor"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(resp.Body).To(MatchJSON(`{"email":"members-member@konflux.dev","role":"contributor"}`))
+
+		binding, err := memberBindingOf(member)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(binding.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "workspace-manager"))
+		Expect(binding.RoleRef.Name).To(Equal("konflux-contributor-user-actions"))
+		Expect(binding.Subjects).To(ConsistOf(HaveField("Name", member)))
+		Expect(lastActivityOf("members-test")).To(BeTemporally(">=", start))
+	})
+
+	It("changes the role of a member", func() {
+		resp := call(http.MethodPost, "", admin, `{"email":"members-member@konflux.dev","role":"maintainer"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		binding, err := memberBindingOf(member)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(binding.RoleRef.Name).To(Equal("konflux-maintainer-user-actions"))
+	})
+
+	It("lists the members granted by the service only", func() {
+		resp := call(http.MethodGet, "", admin, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body).To(MatchJSON(`{"items":[{"email":"members-member@konflux.dev","role":"maintainer"}]}`))
+	})
+
+	It("revokes the role of a member", func() {
+		ns := &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "members-test"}}
+		patch := client.MergeFrom(ns.DeepCopy())
+		ns.Annotations = map[string]string{"konflux.ci/last-activity": "2000-01-01T00:00:00Z"}
+		Expect(k8sClient.Patch(context.Background(), ns, patch)).To(Succeed())
+		start := time.Now().Truncate(time.Second)
+
+		Expect(call(http.MethodDelete, "/"+member, admin, "").StatusCode).To(Equal(http.StatusNoContent))
+		_, err := memberBindingOf(member)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(lastActivityOf("members-test")).To(BeTemporally(">=", start))
+		Expect(call(http.MethodDelete, "/"+member, admin, "").StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("does not revoke the RoleBindings made by hand", func() {
+		Expect(call(http.MethodDelete, "/members-viewer@konflux.dev", admin, "").StatusCode).
+			To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,270 @@
+package workspaces
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+// Orders of the workspace lists
+const (
+	sortByName         = "name"
+	sortByCreationTime = "creationTime"
+	sortByLastActivity = "lastActivity"
+)
+
+// Annotation holding the time of the last activity in a namespace, in RFC 3339
+// format
+const lastActivityAnnotation = "konflux.ci/last-activity"
+
+// continueToken is the content of the continue token of the workspace lists
+type continueToken struct {
+	// Name of the last workspace of the previous page
+	After string `json:"after"`
+	// Sort key of the last workspace of the previous page, for the orders
+	// other than by name
+	AfterKey   string `json:"afterKey,omitempty"`
+	SortBy     string `json:"sortBy,omitempty"`
+	Descending bool   `json:"desc,omitempty"`
+}
+
+// Encode the opaque continue token
+func encodeContinueToken(token continueToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode a token built by encodeContinueToken
+func decodeContinueToken(token string) (continueToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return continueToken{}, errors.New("invalid continue token")
+	}
+	decoded := continueToken{}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.After == "" {
+		return continueToken{}, errors.New("invalid continue token")
+	}
+	return decoded, nil
+}
+
+// workspaceQuery holds the query parameters of a workspace list
+type workspaceQuery struct {
+	// Maximum number of workspaces returned, 0 for no limit
+	Limit int
+	// Position after which the list continues, nil to start at the beginning
+	Continue *continueToken
+	// Filters on the workspace names
+	NamePrefix   string
+	NameContains string
+	// Filter on the labels of the namespaces, nil for no filter
+	Selector labels.Selector
+	// Filter on the role of the caller, empty for no filter
+	Role       string
+	SortBy     string
+	Descending bool
+}
+
+// Read the query parameters of a workspace list, the role filter has to be
+// one of the catalog
+func workspaceListQuery(c echo.Context, catalog roles.Catalog) (workspaceQuery, error) {
+	q := workspaceQuery{
+		NamePrefix:   c.QueryParam("namePrefix"),
+		NameContains: c.QueryParam("nameContains"),
+		Role:         c.QueryParam("role"),
+		SortBy:       sortByName,
+	}
+	if value := c.QueryParam("limit"); value != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(value); err != nil || q.Limit < 0 {
+			return workspaceQuery{}, fmt.Errorf("invalid limit %q, expected a positive number", value)
+		}
+	}
+	if value := c.QueryParam("labelSelector"); value != "" {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return workspaceQuery{}, fmt.Errorf("invalid labelSelector: %w", err)
+		}
+		q.Selector = selector
+	}
+	if q.Role != "" && !catalog.Has(q.Role) {
+		return workspaceQuery{}, fmt.Errorf("unknown role %q", q.Role)
+	}
+	switch sortBy := c.QueryParam("sortBy"); sortBy {
+	case "":
+	case sortByName, sortByCreationTime, sortByLastActivity:
+		q.SortBy = sortBy
+	default:
+		return workspaceQuery{}, fmt.Errorf(
+			"invalid sortBy %q, expected %s, %s or %s", sortBy, sortByName, sortByCreationTime, sortByLastActivity,
+		)
+	}
+	switch order := c.QueryParam("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return workspaceQuery{}, fmt.Errorf("invalid order %q, expected asc or desc", order)
+	}
+	if value := c.QueryParam("continue"); value != "" {
+		token, err := decodeContinueToken(value)
+		if err != nil {
+			return workspaceQuery{}, err
+		}
+		if token.SortBy != q.SortBy || token.Descending != q.Descending {
+			return workspaceQuery{}, errors.New("the continue token was issued for another order")
+		}
+		q.Continue = &token
+	}
+	return q, nil
+}
+
+// Check whether the workspace made of the namespaces passes the filters
+// applied before the access checks. The namespaces not matching the label
+// selector are dropped.
+func (q *workspaceQuery) filter(group []core.Namespace) []core.Namespace {
+	name := workspaceName(group[0])
+	if !strings.HasPrefix(name, q.NamePrefix) || !strings.Contains(name, q.NameContains) {
+		return nil
+	}
+	if q.Selector == nil {
+		return group
+	}
+	var matching []core.Namespace
+	for _, ns := range group {
+		if q.Selector.Matches(labels.Set(ns.Labels)) {
+			matching = append(matching, ns)
+		}
+	}
+	return matching
+}
+
+// Sort key of the workspace made of the namespaces, empty when sorting by name
+// The creation time of a workspace is the one of its oldest namespace, its
+// last activity the latest of its namespaces, defaulting to their creation.
+func (q *workspaceQuery) sortKey(group []core.Namespace) string {
+	if q.SortBy == sortByName {
+		return ""
+	}
+	var key time.Time
+	for i, ns := range group {
+		t := ns.CreationTimestamp.Time
+		if q.SortBy == sortByLastActivity {
+			if activity, err := time.Parse(time.RFC3339, ns.Annotations[lastActivityAnnotation]); err == nil {
+				t = activity
+			}
+		}
+		switch {
+		case i == 0,
+			q.SortBy == sortByCreationTime && t.Before(key),
+			q.SortBy == sortByLastActivity && t.After(key):
+			key = t
+		}
+	}
+	// sorting as strings sorts by time
+	return key.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// Compare the positions of two workspaces in the list, from their sort keys
+// and names
+func (q *workspaceQuery) compare(keyA string, nameA string, keyB string, nameB string) int {
+	result := strings.Compare(keyA, keyB)
+	if result == 0 {
+		result = strings.Compare(nameA, nameB)
+	}
+	if q.Descending {
+		return -result
+	}
+	return result
+}
+
+// Return up to limit workspaces of the calling user, filtered and sorted as
+// the query asks, after the position of its continue token. The filters on
+// the names and labels and the sorting are applied before the access checks,
+// and the access is checked one page worth of workspaces at a time so that the
+// namespaces of the workspaces beyond the page are not checked. The continue
+// token of the returned list is set when more workspaces may follow.
+func (h *Handlers) getWorkspacesPage(c echo.Context, allNamespaces []core.Namespace, q workspaceQuery) (crt.WorkspaceList, error) {
+	type candidate struct {
+		name       string
+		key        string
+		namespaces []core.Namespace
+	}
+	var candidates []candidate
+	for _, group := range groupWorkspaceNamespaces(allNamespaces) {
+		if group = q.filter(group); len(group) > 0 {
+			candidates = append(candidates, candidate{
+				name:       workspaceName(group[0]),
+				key:        q.sortKey(group),
+				namespaces: group,
+			})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return q.compare(candidates[i].key, candidates[i].name, candidates[j].key, candidates[j].name) < 0
+	})
+	position := map[string]int{}
+	for i, candidate := range candidates {
+		position[candidate.name] = i
+	}
+	next := 0
+	if q.Continue != nil {
+		next = sort.Search(len(candidates), func(i int) bool {
+			return q.compare(candidates[i].key, candidates[i].name, q.Continue.AfterKey, q.Continue.After) > 0
+		})
+	}
+
+	var page crt.WorkspaceList
+	for {
+		// the next workspaces, as many as missing on the page
+		end := len(candidates)
+		if q.Limit > 0 && next+q.Limit-len(page.Items) < end {
+			end = next + q.Limit - len(page.Items)
+		}
+		var namespaces []core.Namespace
+		for _, candidate := range candidates[next:end] {
+			namespaces = append(namespaces, candidate.namespaces...)
+		}
+		next = end
+
+		workspaces, err := h.getWorkspacesWithAccess(c, namespaces)
+		if err != nil {
+			return crt.WorkspaceList{}, err
+		}
+		items := page.Items
+		for _, ws := range workspaces.Items {
+			if q.Role == "" || ws.Status.Role == q.Role {
+				items = append(items, ws)
+			}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return position[items[i].Name] < position[items[j].Name]
+		})
+		page = workspaces
+		page.Items = items
+		if next == len(candidates) {
+			return page, nil
+		}
+		if len(page.Items) == q.Limit {
+			last := candidates[position[page.Items[len(page.Items)-1].Name]]
+			page.Continue = encodeContinueToken(continueToken{
+				After:      last.name,
+				AfterKey:   last.key,
+				SortBy:     q.SortBy,
+				Descending: q.Descending,
+			})
+			return page, nil
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package workspaces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+var _ = Describe("GetWorkspacesPage", func() {
+	var (
+		e             *echo.Echo
+		c             echo.Context
+		allNamespaces []core.Namespace
+		checked       []string
+		h             *Handlers
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		c = e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		c.Request().Header.Set("X-Email", "user@konflux.dev")
+		allNamespaces = nil
+		for i := 1; i <= 5; i++ {
+			allNamespaces = append(allNamespaces, core.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("page-%d", i)},
+			})
+		}
+		checked = nil
+		h = newTestHandlers(Options{})
+		// grants access to all namespaces but page-2, recording the checked ones
+		h.namespacesWithAccess = func(c echo.Context, namespaces []core.Namespace) ([]core.Namespace, error) {
+			var allowed []core.Namespace
+			for _, ns := range namespaces {
+				checked = append(checked, ns.Name)
+				if ns.Name != "page-2" {
+					allowed = append(allowed, ns)
+				}
+			}
+			return allowed, nil
+		}
+	})
+
+	workspaceNames := func(list crt.WorkspaceList) []string {
+		var names []string
+		for _, ws := range list.Items {
+			names = append(names, ws.Name)
+		}
+		return names
+	}
+
+	It("returns all the workspaces without limit", func() {
+		list, err := h.getWorkspacesPage(c, allNamespaces, workspaceQuery{SortBy: sortByName})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-1", "page-3", "page-4", "page-5"}))
+		Expect(list.Continue).To(BeEmpty())
+	})
+
+	It("pages through the workspaces without checking beyond the page", func() {
+		query := workspaceQuery{Limit: 2, SortBy: sortByName}
+		list, err := h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-1", "page-3"}))
+		Expect(checked).To(Equal([]string{"page-1", "page-2", "page-3"}))
+		Expect(list.Continue).NotTo(BeEmpty())
+
+		token, err := decodeContinueToken(list.Continue)
+		Expect(err).NotTo(HaveOccurred())
+		query.Continue = &token
+		list, err = h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-4", "page-5"}))
+		Expect(list.Continue).To(BeEmpty())
+	})
+
+	It("rejects an invalid continue token", func() {
+		_, err := decodeContinueToken("not a token")
+		Expect(err).To(MatchError("invalid continue token"))
+	})
+
+	It("filters the workspaces on their names and labels before checking the access", func() {
+		allNamespaces[2].Labels = map[string]string{"team": "a"}
+		allNamespaces[3].Labels = map[string]string{"team": "a"}
+		selector, err := labels.Parse("team=a")
+		Expect(err).NotTo(HaveOccurred())
+		query := workspaceQuery{SortBy: sortByName, NameContains: "-", NamePrefix: "page", Selector: selector}
+		list, err := h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-3", "page-4"}))
+		Expect(checked).To(Equal([]string{"page-3", "page-4"}))
+
+		query = workspaceQuery{SortBy: sortByName, NameContains: "4"}
+		list, err = h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-4"}))
+	})
+
+	It("filters the workspaces on the role of the caller", func() {
+		h.accessCheck = func(_ context.Context, _ string, namespace string, _ accessrules.Check) (bool, error) {
+			return namespace == "page-3" || namespace == "page-5", nil
+		}
+		query := workspaceQuery{SortBy: sortByName, Role: "admin", Limit: 1}
+		list, err := h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-3"}))
+		Expect(list.Items[0].Status.Role).To(Equal("admin"))
+		Expect(list.Continue).NotTo(BeEmpty())
+	})
+
+	It("sorts the workspaces by creation time and last activity", func() {
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := range allNamespaces {
+			// created from the last to the first
+			allNamespaces[i].CreationTimestamp = metav1.NewTime(start.Add(time.Duration(-i) * time.Hour))
+		}
+		allNamespaces[0].Annotations = map[string]string{
+			"konflux.ci/last-activity": start.Add(time.Hour).Format(time.RFC3339),
+		}
+
+		query := workspaceQuery{SortBy: sortByCreationTime, Limit: 2}
+		list, err := h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-5", "page-4"}))
+		token, err := decodeContinueToken(list.Continue)
+		Expect(err).NotTo(HaveOccurred())
+		query.Continue = &token
+		list, err = h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-3", "page-1"}))
+
+		query = workspaceQuery{SortBy: sortByLastActivity, Descending: true}
+		list, err = h.getWorkspacesPage(c, allNamespaces, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workspaceNames(list)).To(Equal([]string{"page-1", "page-3", "page-4", "page-5"}))
+	})
+
+	DescribeTable("reading the query parameters",
+		func(query string, expected workspaceQuery) {
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/workspaces?"+query, nil), httptest.NewRecorder())
+			Expect(workspaceListQuery(c, roles.Default())).To(Equal(expected))
+		},
+		Entry("without parameters", "", workspaceQuery{SortBy: sortByName}),
+		Entry("with filters", "namePrefix=dev&nameContains=team&role=viewer",
+			workspaceQuery{SortBy: sortByName, NamePrefix: "dev", NameContains: "team", Role: "viewer"},
+		),
+		Entry("with an order", "sortBy=lastActivity&order=desc&limit=10",
+			workspaceQuery{SortBy: sortByLastActivity, Descending: true, Limit: 10},
+		),
+	)
+
+	DescribeTable("rejecting invalid query parameters",
+		func(query string) {
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/workspaces?"+query, nil), httptest.NewRecorder())
+			_, err := workspaceListQuery(c, roles.Default())
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("with an unknown role", "role=owner"),
+		Entry("with an unknown order", "sortBy=size"),
+		Entry("with an unknown direction", "order=up"),
+		Entry("with an invalid label selector", "labelSelector=a%3D%3D%3Db"),
+		Entry("with a continue token for another order", "sortBy=creationTime&continue="+
+			encodeContinueToken(continueToken{After: "page-1", SortBy: sortByName})),
+	)
+})
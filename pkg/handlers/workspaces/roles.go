@@ -0,0 +1,83 @@
+package workspaces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+// roleDescription describes a role of the catalog
+type roleDescription struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Name of the ClusterRole the members holding the role are bound to
+	ClusterRole string              `json:"clusterRole"`
+	Rules       []rbacv1.PolicyRule `json:"rules"`
+}
+
+// roleDescriptionList lists the roles from the least to the most privileged
+type roleDescriptionList struct {
+	Items []roleDescription `json:"items"`
+}
+
+// Describe the roles the users may hold in the workspaces
+func (h *Handlers) RolesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, describeRoles(h.opts.Roles))
+}
+
+// Describe the roles of the catalog
+func describeRoles(catalog roles.Catalog) roleDescriptionList {
+	list := roleDescriptionList{Items: []roleDescription{}}
+	for _, role := range catalog {
+		list.Items = append(list.Items, roleDescription{
+			Name:        role.Name,
+			Description: role.Description,
+			ClusterRole: role.ClusterRole.Name,
+			Rules:       role.ClusterRole.Rules,
+		})
+	}
+	return list
+}
+
+// OwnerClusterRole returns the name of the ClusterRole of the admin role of
+// the catalog, which the owners of the workspaces are bound to
+func OwnerClusterRole(catalog roles.Catalog) string {
+	admin, _ := catalog.Get(roles.Admin)
+	return admin.ClusterRole.Name
+}
+
+// ApplyRoleTemplates creates or updates the ClusterRoles of the roles from
+// their templates
+func ApplyRoleTemplates(ctx context.Context, cl client.Client, catalog roles.Catalog) error {
+	for _, role := range catalog {
+		template := role.ClusterRole.DeepCopy()
+		if template.Labels == nil {
+			template.Labels = map[string]string{}
+		}
+		template.Labels[managedByLabel] = managedBy
+		existing := &rbacv1.ClusterRole{}
+		err := cl.Get(ctx, client.ObjectKey{Name: template.Name}, existing)
+		if apierrors.IsNotFound(err) {
+			if err := cl.Create(ctx, template); err != nil {
+				return fmt.Errorf("failed to create the ClusterRole of role %s: %w", role.Name, err)
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		existing.Labels = template.Labels
+		existing.Annotations = template.Annotations
+		existing.Rules = template.Rules
+		if err := cl.Update(ctx, existing); err != nil {
+			return fmt.Errorf("failed to update the ClusterRole of role %s: %w", role.Name, err)
+		}
+	}
+	return nil
+}
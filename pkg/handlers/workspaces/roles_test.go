@@ -0,0 +1,32 @@
+package workspaces
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+var _ = Describe("Role templates", func() {
+	It("updates the ClusterRoles from their templates", func() {
+		catalog := func(verbs string) roles.Catalog {
+			catalog, err := roles.Parse([]byte("roles:\n- name: reader\n  clusterRole:\n" +
+				"    metadata: {name: roles-test-reader}\n" +
+				"    rules: [{apiGroups: [appstudio.redhat.com], resources: [applications], verbs: " + verbs + "}]\n"))
+			Expect(err).NotTo(HaveOccurred())
+			return catalog
+		}
+		Expect(ApplyRoleTemplates(context.Background(), k8sClient, catalog("[list]"))).To(Succeed())
+		Expect(ApplyRoleTemplates(context.Background(), k8sClient, catalog("[list, watch]"))).To(Succeed())
+
+		clusterRole := &rbacv1.ClusterRole{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "roles-test-reader"}, clusterRole)).
+			To(Succeed())
+		Expect(clusterRole.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "workspace-manager"))
+		Expect(clusterRole.Rules).To(ConsistOf(HaveField("Verbs", []string{"list", "watch"})))
+	})
+})
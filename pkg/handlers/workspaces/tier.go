@@ -0,0 +1,65 @@
+package workspaces
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	signupnamespace "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+)
+
+// workspaceTierChange is the body of the requests changing the tier of a
+// workspace
+type workspaceTierChange struct {
+	Tier string `json:"tier"`
+}
+
+// Apply a tier to all the namespaces of a workspace, regardless of the
+// access of the calling user to the workspace. The objects of the previous
+// tier of the namespaces are deleted.
+func (h *Handlers) ChangeTierHandler(c echo.Context) error {
+	change := workspaceTierChange{}
+	if err := c.Bind(&change); err != nil {
+		return err
+	}
+	tier, found := h.opts.Tiers.Get(change.Tier)
+	if !found {
+		return echo.NewHTTPError(
+			http.StatusBadRequest,
+			fmt.Sprintf("unknown tier %q, available tiers: %s", change.Tier, strings.Join(h.opts.Tiers.Names(), ", ")),
+		)
+	}
+	name := c.Param("ws")
+	wsNamespaces, err := getWorkspaceNamespaces(h.namespaces, name)
+	if errors.Is(err, errNamespaceCacheNotSynced) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	} else if err != nil {
+		return err
+	}
+	if len(wsNamespaces) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	ctx := c.Request().Context()
+	changed := []core.Namespace{}
+	for _, ns := range wsNamespaces {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		owner := ns.Annotations[signupnamespace.OwnerAnnotation]
+		if err := tier.Apply(ctx, h.client, ns.Name, owner); err != nil {
+			return err
+		}
+		changed = append(changed, ns)
+	}
+	if err := h.recordActivity(ctx, changed); err != nil {
+		return err
+	}
+	c.Logger().Infof("changed the tier of workspace %s to %s for %s", name, tier.Name, auth.Email(c))
+	return c.JSON(http.StatusOK, &change)
+}
@@ -0,0 +1,127 @@
+package workspaces
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	signupnamespace "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+)
+
+var _ = Describe("Workspace tier", Ordered, func() {
+	var (
+		server     *httptest.Server
+		namespaces *NamespaceCache
+		h          *Handlers
+	)
+	owner := "tier-owner@konflux.dev"
+
+	BeforeAll(func() {
+		catalog, err := tiers.Parse([]byte(`
+tiers:
+- name: base
+  serviceAccounts:
+  - metadata: {name: pipeline}
+- name: large
+  resourceQuotas:
+  - metadata: {name: compute}
+    spec:
+      hard: {requests.cpu: "8"}
+  roleBindings:
+  - metadata: {name: owner-view}
+    subjects: [{kind: User, apiGroup: rbac.authorization.k8s.io, name: "${OWNER}"}]
+    roleRef: {kind: ClusterRole, apiGroup: rbac.authorization.k8s.io, name: konflux-viewer-user-actions}
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(signupnamespace.ProvisionNamespace(
+			context.Background(), k8sClient, "tier-test", owner, OwnerClusterRole(roles.Default()), catalog.DefaultTier(),
+		)).To(Succeed())
+
+		namespaces = startTestNamespaceCache()
+
+		h = newTestHandlers(Options{Namespaces: namespaces, Tiers: catalog})
+
+		e := echo.New()
+		e.PUT("/api/v1/admin/workspaces/:ws/tier", h.ChangeTierHandler)
+		server = httptest.NewServer(e)
+		DeferCleanup(server.Close)
+	})
+
+	// Request the change of the tier of a workspace
+	change := func(ws string, body string) int {
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/api/v1/admin/workspaces/"+ws+"/tier",
+			strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-Email", "tier-admin@konflux.dev")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	It("applies the default tier when provisioning", func() {
+		account := &core.ServiceAccount{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "tier-test", Name: "pipeline"}, account)).
+			To(Succeed())
+		Expect(account.Labels).To(HaveKeyWithValue("konflux.ci/tier", "base"))
+	})
+
+	It("rejects an unknown tier", func() {
+		Expect(change("tier-test", `{"tier":"gold"}`)).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects an unknown workspace", func() {
+		Expect(change("tier-test-missing", `{"tier":"large"}`)).To(Equal(http.StatusNotFound))
+	})
+
+	It("replaces the objects of the previous tier", func() {
+		Eventually(func() (string, error) {
+			ns, err := getWorkspaceNamespaces(namespaces, "tier-test")
+			if err != nil || len(ns) == 0 {
+				return "", err
+			}
+			return ns[0].Labels["konflux.ci/tier"], nil
+		}).Should(Equal("base"))
+		start := time.Now().Truncate(time.Second)
+		Expect(change("tier-test", `{"tier":"large"}`)).To(Equal(http.StatusOK))
+		Expect(lastActivityOf("tier-test")).To(BeTemporally(">=", start))
+
+		quota := &core.ResourceQuota{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "tier-test", Name: "compute"}, quota)).
+			To(Succeed())
+		binding := &rbacv1.RoleBinding{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "tier-test", Name: "owner-view"}, binding)).
+			To(Succeed())
+		Expect(binding.Subjects).To(ConsistOf(HaveField("Name", owner)))
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "tier-test", Name: "pipeline"},
+			&core.ServiceAccount{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("reports the tier of the workspace", func() {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		c.Request().Header.Set("X-Email", owner)
+		Eventually(func() (map[string]string, error) {
+			ws, err := h.getWorkspace(c, "tier-test")
+			if err != nil || ws == nil {
+				return nil, err
+			}
+			return ws.Labels, nil
+		}).Should(HaveKeyWithValue("konflux.ci/tier", "large"))
+	})
+})
@@ -0,0 +1,254 @@
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/konflux-ci/workspace-manager/pkg/changes"
+)
+
+// RecordChanges records the changes of the workspaces in log: the changes of
+// their namespaces and of the RoleBindings in them. The objects that existed
+// before are not recorded. The factory has to be started afterwards.
+func RecordChanges(
+	ctx context.Context, namespaces *NamespaceCache, factory informers.SharedInformerFactory, log *changes.Log,
+) {
+	// the creation timestamps are truncated to the second
+	started := time.Now().Truncate(time.Second)
+	existing := func(obj metav1.Object) bool {
+		return obj.GetCreationTimestamp().Time.Before(started)
+	}
+
+	recordNamespace := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if ns, ok := obj.(*core.Namespace); ok {
+			log.Record(workspaceName(*ns))
+		}
+	}
+	namespaces.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*core.Namespace); ok && !existing(ns) {
+				recordNamespace(obj)
+			}
+		},
+		UpdateFunc: func(oldObj, obj interface{}) {
+			// the namespace may have moved to another workspace
+			recordNamespace(oldObj)
+			recordNamespace(obj)
+		},
+		DeleteFunc: recordNamespace,
+	})
+
+	// the authorizer of the API server may see the changes of the
+	// RoleBindings after the watches, so the workspaces are recorded again a
+	// bit later. The changes of a workspace during the delay are recorded
+	// again once.
+	delayed := workqueue.NewNamedDelayingQueue("workspace-changes")
+	go func() {
+		<-ctx.Done()
+		delayed.ShutDown()
+	}()
+	go func() {
+		for {
+			name, shutdown := delayed.Get()
+			if shutdown {
+				return
+			}
+			log.Record(name.(string))
+			delayed.Done(name)
+		}
+	}()
+	recordRoleBinding := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		binding, ok := obj.(*rbacv1.RoleBinding)
+		if !ok {
+			return
+		}
+		ns, err := namespaces.lister.Get(binding.Namespace)
+		if err != nil {
+			// not a user namespace
+			return
+		}
+		name := workspaceName(*ns)
+		log.Record(name)
+		delayed.AddAfter(name, RBACPropagationDelay)
+	}
+	factory.Rbac().V1().RoleBindings().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if binding, ok := obj.(*rbacv1.RoleBinding); ok && !existing(binding) {
+				recordRoleBinding(obj)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) { recordRoleBinding(obj) },
+		DeleteFunc: recordRoleBinding,
+	})
+}
+
+// workspaceEvent is a watch event of a workspace, or a Status for errors
+type workspaceEvent struct {
+	Type   watch.EventType `json:"type"`
+	Object interface{}     `json:"object"`
+}
+
+// Stream the changes of the workspaces of the calling user as watch events in
+// newline-delimited JSON. Without resourceVersion query parameter, the
+// current workspaces are sent first as ADDED events. Otherwise the watch
+// resumes after the resource version, the workspaces changed since then being
+// sent as MODIFIED. Which workspaces the user could see before is unknown then,
+// so those the user no longer has access to are not sent, as the names of the
+// workspaces of the other users would leak. BOOKMARK events carrying the
+// current resource version are sent every heartbeat.
+func (h *Handlers) watchWorkspaces(c echo.Context) error {
+	ctx := c.Request().Context()
+	log := h.opts.Changes
+	notifications, cancel := log.Subscribe()
+	defer cancel()
+
+	resumed := c.QueryParam("resourceVersion") != ""
+	resourceVersion := c.QueryParam("resourceVersion")
+	if resumed {
+		if _, _, err := log.Since(resourceVersion); err != nil {
+			return echo.NewHTTPError(http.StatusGone, err.Error())
+		}
+	} else {
+		resourceVersion = log.ResourceVersion()
+	}
+
+	// the workspaces as last sent to the user
+	nameReq, _ := labels.NewRequirement("kubernetes.io/metadata.name", selection.Exists, []string{})
+	userNamespaces, err := getUserNamespaces(h.namespaces, *nameReq)
+	if errors.Is(err, errNamespaceCacheNotSynced) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	} else if err != nil {
+		return err
+	}
+	current, err := h.getWorkspacesWithAccess(c, userNamespaces)
+	if err != nil {
+		return err
+	}
+	sent := map[string]crt.Workspace{}
+	for _, ws := range current.Items {
+		sent[ws.Name] = ws
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	c.Response().WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(c.Response())
+	send := func(eventType watch.EventType, object interface{}) error {
+		if err := encoder.Encode(workspaceEvent{Type: eventType, Object: object}); err != nil {
+			return err
+		}
+		c.Response().Flush()
+		return nil
+	}
+	gv := crt.GroupVersion.String()
+	if !resumed {
+		for _, ws := range current.Items {
+			ws.ResourceVersion = resourceVersion
+			if err := send(watch.Added, ws); err != nil {
+				return err
+			}
+		}
+	}
+
+	// send the changes since resourceVersion, the previous state of the
+	// workspaces changed before a resumed watch is unknown. Only the
+	// workspaces sent on this watch are sent as DELETED.
+	sendChanges := func(unknown bool) error {
+		names, next, err := log.Since(resourceVersion)
+		if err != nil {
+			status := metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Message:  err.Error(),
+				Reason:   metav1.StatusReasonExpired,
+				Code:     http.StatusGone,
+			}
+			_ = send(watch.Error, status)
+			return err
+		}
+		for _, name := range names {
+			ws, err := h.getWorkspace(c, name)
+			if err != nil {
+				return err
+			}
+			previous, found := sent[name]
+			var eventType watch.EventType
+			var object crt.Workspace
+			switch {
+			case ws != nil && !found && !unknown:
+				eventType, object = watch.Added, *ws
+			case ws != nil && (unknown || !equality.Semantic.DeepEqual(previous, *ws)):
+				eventType, object = watch.Modified, *ws
+			case ws == nil && found:
+				eventType, object = watch.Deleted, previous
+			default:
+				continue
+			}
+			if ws != nil {
+				sent[name] = *ws
+			} else {
+				delete(sent, name)
+			}
+			object.ResourceVersion = next
+			if err := send(eventType, object); err != nil {
+				return err
+			}
+		}
+		resourceVersion = next
+		return nil
+	}
+	// the response has started, the errors can only be logged
+	stop := func(err error) error {
+		if ctx.Err() == nil && !errors.Is(err, changes.ErrExpired) {
+			c.Logger().Error(err)
+		}
+		return nil
+	}
+	if resumed {
+		if err := sendChanges(true); err != nil {
+			return stop(err)
+		}
+	}
+
+	ticker := time.NewTicker(h.opts.WatchHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notifications:
+			if err := sendChanges(false); err != nil {
+				return stop(err)
+			}
+		case <-ticker.C:
+			bookmark := crt.Workspace{
+				TypeMeta:   metav1.TypeMeta{Kind: "Workspace", APIVersion: gv},
+				ObjectMeta: metav1.ObjectMeta{ResourceVersion: resourceVersion},
+			}
+			if err := send(watch.Bookmark, bookmark); err != nil {
+				return stop(err)
+			}
+		}
+	}
+}
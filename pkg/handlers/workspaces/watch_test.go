@@ -0,0 +1,171 @@
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/konflux-ci/workspace-manager/pkg/changes"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+// watchedEvent is a decoded workspace watch event
+type watchedEvent struct {
+	Type   string        `json:"type"`
+	Object crt.Workspace `json:"object"`
+}
+
+var _ = Describe("Workspace watch", Ordered, func() {
+	var (
+		server    *httptest.Server
+		changeLog *changes.Log
+	)
+	user := "watch-user@konflux.dev"
+
+	BeforeAll(func() {
+		for _, name := range []string{"watch-test-1", "watch-test-2"} {
+			_, err := utils.CreateNamespace(k8sClient, name)
+			Expect(err).NotTo(HaveOccurred())
+			utils.CreateRole(k8sClient, name, "watch-access", []string{"list", "watch"})
+		}
+		utils.CreateRoleBinding(k8sClient, "watch-access-binding", "watch-test-1", user, "watch-access")
+
+		namespaces := startTestNamespaceCache()
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		factory := informers.NewSharedInformerFactory(k8sClientset, 0)
+		changeLog = changes.NewLog(100)
+		RecordChanges(ctx, namespaces, factory, changeLog)
+		factory.Start(ctx.Done())
+		Expect(cache.WaitForCacheSync(ctx.Done(), factory.Rbac().V1().RoleBindings().Informer().HasSynced)).
+			To(BeTrue())
+
+		h := newTestHandlers(Options{Namespaces: namespaces, Changes: changeLog, WatchHeartbeat: 200 * time.Millisecond})
+
+		e := echo.New()
+		e.GET("/workspaces", h.ListHandler)
+		server = httptest.NewServer(e)
+		DeferCleanup(server.Close)
+	})
+
+	// Start a watch, the events are sent to the returned channel
+	startWatch := func(query string) (<-chan watchedEvent, *http.Response) {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/workspaces?watch=true&"+query, nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("X-Email", user)
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		events := make(chan watchedEvent, 100)
+		go func() {
+			defer close(events)
+			decoder := json.NewDecoder(resp.Body)
+			for {
+				event := watchedEvent{}
+				if err := decoder.Decode(&event); err != nil {
+					return
+				}
+				events <- event
+			}
+		}()
+		return events, resp
+	}
+
+	// Wait for the next event that is not a bookmark
+	nextEvent := func(events <-chan watchedEvent) watchedEvent {
+		for {
+			var event watchedEvent
+			Eventually(events, 10*time.Second).Should(Receive(&event))
+			if event.Type != "BOOKMARK" {
+				return event
+			}
+		}
+	}
+
+	It("streams the workspaces and their changes", func() {
+		events, resp := startWatch("")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+
+		event := nextEvent(events)
+		Expect(event.Type).To(Equal("ADDED"))
+		Expect(event.Object.Name).To(Equal("watch-test-1"))
+		Expect(event.Object.ResourceVersion).NotTo(BeEmpty())
+
+		utils.CreateRoleBinding(k8sClient, "watch-access-binding", "watch-test-2", user, "watch-access")
+		event = nextEvent(events)
+		Expect(event.Type).To(Equal("ADDED"))
+		Expect(event.Object.Name).To(Equal("watch-test-2"))
+
+		binding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{
+			Name: "watch-access-binding", Namespace: "watch-test-2",
+		}}
+		Expect(k8sClient.Delete(context.Background(), binding)).To(Succeed())
+		event = nextEvent(events)
+		Expect(event.Type).To(Equal("DELETED"))
+		Expect(event.Object.Name).To(Equal("watch-test-2"))
+	})
+
+	It("sends heartbeats", func() {
+		events, _ := startWatch("")
+		Eventually(events, 5*time.Second).Should(Receive(HaveField("Type", "BOOKMARK")))
+	})
+
+	It("resumes from a resource version", func() {
+		resourceVersion := changeLog.ResourceVersion()
+		utils.CreateRoleBinding(k8sClient, "watch-access-binding-resumed", "watch-test-2", user, "watch-access")
+		// the workspace changed before the watch started
+		Eventually(func() ([]string, error) {
+			names, _, err := changeLog.Since(resourceVersion)
+			return names, err
+		}).Should(ContainElement("watch-test-2"))
+
+		events, _ := startWatch("resourceVersion=" + resourceVersion)
+		event := nextEvent(events)
+		Expect(event.Type).To(Equal("MODIFIED"))
+		Expect(event.Object.Name).To(Equal("watch-test-2"))
+	})
+
+	It("does not reveal the workspaces of the other users when resuming", func() {
+		resourceVersion := changeLog.ResourceVersion()
+		_, err := utils.CreateNamespace(k8sClient, "watch-test-other")
+		Expect(err).NotTo(HaveOccurred())
+		utils.CreateRole(k8sClient, "watch-test-other", "watch-access", []string{"list", "watch"})
+		utils.CreateRoleBinding(k8sClient, "watch-access-binding", "watch-test-other", "watch-other@konflux.dev",
+			"watch-access")
+		// the workspace of the other user changed before the watch started
+		Eventually(func() ([]string, error) {
+			names, _, err := changeLog.Since(resourceVersion)
+			return names, err
+		}).Should(ContainElement("watch-test-other"))
+
+		// the changes since the resource version are sent before the first heartbeat
+		events, _ := startWatch("resourceVersion=" + resourceVersion)
+		for {
+			var event watchedEvent
+			Eventually(events, 10*time.Second).Should(Receive(&event))
+			if event.Type == "BOOKMARK" {
+				break
+			}
+			Expect(event.Object.Name).NotTo(Equal("watch-test-other"))
+		}
+	})
+
+	It("rejects an expired resource version", func() {
+		_, resp := startWatch("resourceVersion=expired-1")
+		Expect(resp.StatusCode).To(Equal(http.StatusGone))
+	})
+})
@@ -0,0 +1,392 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/kubernetes"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accesscache"
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/konflux-ci/workspace-manager/pkg/changes"
+	signupnamespace "github.com/konflux-ci/workspace-manager/pkg/handlers/signup/namespace"
+	"github.com/konflux-ci/workspace-manager/pkg/rbac"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+)
+
+const (
+	// Number of concurrent access checks per request when
+	// Options.AccessCheckWorkers is not set
+	DefaultAccessCheckWorkers = 16
+	// Interval of the heartbeats of the watches when Options.WatchHeartbeat
+	// is not set
+	DefaultWatchHeartbeat = 30 * time.Second
+)
+
+// Signups tells whether the users completed their signup, as the signup
+// backends do
+type Signups interface {
+	Active(ctx context.Context, email string) (bool, error)
+}
+
+// Options holds the dependencies and settings of the workspace handlers
+type Options struct {
+	Client    client.Client
+	Clientset kubernetes.Interface
+	// Cache of the user namespaces the workspaces are made of
+	Namespaces *NamespaceCache
+	// Lister of the RoleBindings, the owners and the roles of the users are
+	// found from
+	RoleBindings rbaclisters.RoleBindingLister
+	// Log of the workspace changes the watches are served from, filled by
+	// RecordChanges
+	Changes *changes.Log
+	// Signups of the users, only the users whose signup is complete may
+	// create workspaces
+	Signups Signups
+	// Actions the users have to be allowed to perform in a namespace to
+	// access it as a workspace
+	AccessRules *accessrules.RuleSet
+	// Roles the users may hold in the workspaces, the admin role has to be
+	// defined
+	Roles roles.Catalog
+	// Tiers of the user namespaces, the new workspaces get the default tier
+	Tiers *tiers.Catalog
+	// Authorizer evaluating the RBAC rules locally, the access is checked
+	// with SubjectAccessReviews when it is nil
+	Authorizer *rbac.Authorizer
+	// Cache of the decisions of the SubjectAccessReviews, nil when caching
+	// is disabled
+	AccessDecisions *accesscache.Cache
+	// Maximum number of namespaces whose access is checked concurrently for
+	// a request
+	AccessCheckWorkers int
+	// Maximum number of workspaces a user may create besides the namespace
+	// provisioned on signup, no limit applies when it is 0
+	WorkspaceLimit int
+	// Interval of the BOOKMARK events sent on the watches
+	WatchHeartbeat time.Duration
+}
+
+// Handlers serves the workspaces of the users, made of the user namespaces
+// they have access to
+type Handlers struct {
+	client     client.Client
+	clientset  kubernetes.Interface
+	namespaces *NamespaceCache
+	opts       Options
+	// How the namespaces the calling user has access to are found
+	namespacesWithAccess namespacesWithAccess
+	// How the access of the users is checked when computing their roles
+	accessCheck accessCheck
+}
+
+// New creates the workspace handlers from the given options
+func New(opts Options) (*Handlers, error) {
+	if opts.Client == nil || opts.Clientset == nil {
+		return nil, errors.New("the workspace handlers require kubernetes clients")
+	}
+	if opts.Namespaces == nil {
+		return nil, errors.New("the workspace handlers require a namespace cache")
+	}
+	if opts.RoleBindings == nil {
+		return nil, errors.New("the workspace handlers require a RoleBinding lister")
+	}
+	if opts.Changes == nil {
+		return nil, errors.New("the workspace handlers require a log of the workspace changes")
+	}
+	if opts.Signups == nil {
+		return nil, errors.New("the workspace handlers require the signups of the users")
+	}
+	if opts.AccessRules == nil {
+		opts.AccessRules = accessrules.Default()
+	}
+	if opts.Roles == nil {
+		opts.Roles = roles.Default()
+	}
+	if !opts.Roles.Has(roles.Admin) {
+		return nil, fmt.Errorf("the %s role is not defined", roles.Admin)
+	}
+	if opts.Tiers == nil {
+		opts.Tiers = tiers.Default()
+	}
+	if opts.AccessCheckWorkers == 0 {
+		opts.AccessCheckWorkers = DefaultAccessCheckWorkers
+	}
+	if opts.WatchHeartbeat == 0 {
+		opts.WatchHeartbeat = DefaultWatchHeartbeat
+	}
+	h := &Handlers{
+		client:     opts.Client,
+		clientset:  opts.Clientset,
+		namespaces: opts.Namespaces,
+		opts:       opts,
+	}
+	if opts.Authorizer != nil {
+		h.accessCheck = localAccessCheck(opts.Authorizer)
+		h.namespacesWithAccess = h.localNamespacesWithAccess(opts.Authorizer)
+	} else {
+		h.accessCheck = h.sarAccessCheck
+		h.namespacesWithAccess = h.getNamespacesWithAccess
+	}
+	return h, nil
+}
+
+// List the workspaces of the calling user, or watch them when the watch query
+// parameter is true
+func (h *Handlers) ListHandler(c echo.Context) error {
+	if c.QueryParam("watch") == "true" {
+		return h.watchWorkspaces(c)
+	}
+	// taken first so that the changes made while listing are not missed
+	resourceVersion := h.opts.Changes.ResourceVersion()
+	nameReq, _ := labels.NewRequirement(
+		"kubernetes.io/metadata.name", selection.Exists, []string{},
+	)
+	userNamespaces, err := getUserNamespaces(h.namespaces, *nameReq)
+	if errors.Is(err, errNamespaceCacheNotSynced) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	} else if err != nil {
+		return err
+	}
+	query, err := workspaceListQuery(c, h.opts.Roles)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	workspaces, err := h.getWorkspacesPage(c, userNamespaces, query)
+	if err != nil {
+		// the request went away while the access was being checked
+		return err
+	}
+	workspaces.ResourceVersion = resourceVersion
+
+	return c.JSON(http.StatusOK, &workspaces)
+}
+
+// Get the workspace given in the ws path parameter
+func (h *Handlers) GetHandler(c echo.Context) error {
+	userNamespaces, err := getWorkspaceNamespaces(h.namespaces, c.Param("ws"))
+	if errors.Is(err, errNamespaceCacheNotSynced) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	} else if err != nil {
+		return err
+	}
+	workspaces, err := h.getWorkspacesWithAccess(c, userNamespaces)
+	if err != nil {
+		// the request went away while the access was being checked
+		return err
+	}
+	wsParam := c.Param("ws")
+	for _, ws := range workspaces.Items {
+		if ws.Name == wsParam {
+			return c.JSON(http.StatusOK, &ws)
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusNotFound)
+}
+
+// Given all relevant user namespaces, return all workspaces for the calling user
+func (h *Handlers) getWorkspacesWithAccess(c echo.Context, allNamespaces []core.Namespace) (crt.WorkspaceList, error) {
+	user := c.Request().Header.Get(auth.EmailHeader)
+	// the RoleBindings of a terminating namespace are deleted first, its
+	// owner keeps seeing it until it is gone
+	listed := map[string]bool{}
+	var toCheck []core.Namespace
+	for _, ns := range allNamespaces {
+		if ns.DeletionTimestamp != nil && strings.EqualFold(ns.Annotations[signupnamespace.OwnerAnnotation], user) {
+			listed[ns.Name] = true
+		} else {
+			toCheck = append(toCheck, ns)
+		}
+	}
+	allowed, err := h.namespacesWithAccess(c, toCheck)
+	if err != nil {
+		return crt.WorkspaceList{}, err
+	}
+	for _, ns := range allowed {
+		listed[ns.Name] = true
+	}
+	var namespaces []core.Namespace
+	for _, ns := range allNamespaces {
+		if listed[ns.Name] {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	gv := crt.GroupVersion.String()
+
+	var wss []crt.Workspace
+	groups := groupWorkspaceNamespaces(namespaces)
+	for _, group := range groups {
+		ws := crt.Workspace{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Workspace",
+				APIVersion: gv,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: workspaceName(group[0]),
+				// the workspace is terminating once its first namespace is
+				DeletionTimestamp: group[0].DeletionTimestamp,
+			},
+		}
+		// the Workspace status has no tier, it is labelled with the one of
+		// its first namespace instead
+		if tier := group[0].Labels[tiers.TierLabel]; tier != "" {
+			ws.Labels = map[string]string{tiers.TierLabel: tier}
+		}
+		for _, ns := range group {
+			ws.Status.Namespaces = append(ws.Status.Namespaces, crt.SpaceNamespace{
+				Name: ns.Name,
+				Type: namespaceType(ns),
+			})
+		}
+		wss = append(wss, ws)
+	}
+
+	// the owner and the role are the ones of the first namespace of the workspace
+	ctx := c.Request().Context()
+	err = forEachConcurrently(ctx, h.opts.AccessCheckWorkers, len(groups), func(i int) {
+		ns := groups[i][0]
+		owner, err := h.workspaceOwner(ns)
+		if err != nil && ctx.Err() == nil {
+			c.Logger().Errorf("failed to find the owner of %s: %v", ns.Name, err)
+		}
+		role, err := h.workspaceRole(ctx, user, ns.Name)
+		if err != nil && ctx.Err() == nil {
+			c.Logger().Errorf("failed to find the role of %s in %s: %v", user, ns.Name, err)
+		}
+		wss[i].Status.Owner = owner
+		wss[i].Status.Role = role
+	})
+	if err != nil {
+		return crt.WorkspaceList{}, err
+	}
+
+	workspaces := crt.WorkspaceList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "WorkspaceList",
+			APIVersion: gv,
+		},
+		Items: wss,
+	}
+	return workspaces, nil
+}
+
+// Get the workspace of the calling user with the given name, nil if the user
+// has no access to it
+func (h *Handlers) getWorkspace(c echo.Context, name string) (*crt.Workspace, error) {
+	wsNamespaces, err := getWorkspaceNamespaces(h.namespaces, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(wsNamespaces) == 0 {
+		return nil, nil
+	}
+	workspaces, err := h.getWorkspacesWithAccess(c, wsNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	for _, ws := range workspaces.Items {
+		if ws.Name == name {
+			return &ws, nil
+		}
+	}
+	return nil, nil
+}
+
+const (
+	// Label grouping namespaces into the workspace it names. Namespaces
+	// without the label are workspaces of their own, named after them.
+	workspaceLabel = "konflux.ci/workspace"
+	// Label giving the type of a namespace in its workspace, such as dev or
+	// managed
+	namespaceTypeLabel = "konflux.ci/namespace-type"
+	// Type of the namespaces without namespaceTypeLabel
+	defaultNamespaceType = "default"
+)
+
+// Annotate the namespaces of a workspace the service changed with the current
+// time as their last activity, the namespaces deleted meanwhile are skipped
+func (h *Handlers) recordActivity(ctx context.Context, namespaces []core.Namespace) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i := range namespaces {
+		ns := &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaces[i].Name}}
+		patch := client.MergeFrom(ns.DeepCopy())
+		ns.Annotations = map[string]string{lastActivityAnnotation: now}
+		if err := h.client.Patch(ctx, ns, patch); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Name of the workspace the namespace belongs to
+func workspaceName(ns core.Namespace) string {
+	if name := ns.Labels[workspaceLabel]; name != "" {
+		return name
+	}
+	return ns.Name
+}
+
+// Type of the namespace in its workspace
+func namespaceType(ns core.Namespace) string {
+	if nsType := ns.Labels[namespaceTypeLabel]; nsType != "" {
+		return nsType
+	}
+	return defaultNamespaceType
+}
+
+// Group the namespaces by workspace, sorted by workspace name. The first
+// namespace of a workspace is the one named after it, else one of the default
+// type, the others follow sorted by name.
+func groupWorkspaceNamespaces(namespaces []core.Namespace) [][]core.Namespace {
+	byName := map[string][]core.Namespace{}
+	var names []string
+	for _, ns := range namespaces {
+		name := workspaceName(ns)
+		if _, found := byName[name]; !found {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], ns)
+	}
+	sort.Strings(names)
+
+	groups := make([][]core.Namespace, 0, len(names))
+	for _, name := range names {
+		group := byName[name]
+		rank := func(ns core.Namespace) int {
+			switch {
+			case ns.Name == name:
+				return 0
+			case namespaceType(ns) == defaultNamespaceType:
+				return 1
+			}
+			return 2
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			if rank(group[i]) != rank(group[j]) {
+				return rank(group[i]) < rank(group[j])
+			}
+			return group[i].Name < group[j].Name
+		})
+		groups = append(groups, group)
+	}
+	return groups
+}
@@ -0,0 +1,410 @@
+package workspaces
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	crt "github.com/codeready-toolchain/api/api/v1alpha1"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/changes"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+)
+
+var k8sClient client.Client
+var k8sClientset kubernetes.Interface
+var testEnv *envtest.Environment
+
+// Lister of the RoleBindings of the test cluster, shared by the handlers of
+// the specs
+var roleBindings rbaclisters.RoleBindingLister
+var stopInformers context.CancelFunc
+
+func TestWorkspaces(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Workspaces Suite")
+}
+
+var _ = BeforeSuite(func() {
+	schema := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(schema))
+	utilruntime.Must(v1alpha1.AddToScheme(schema))
+	testEnv = &envtest.Environment{
+		BinaryAssetsDirectory: "../../../bin/k8s/1.29.0-linux-amd64/",
+		CRDDirectoryPaths:     []string{"../../../config/crd/bases"},
+		ErrorIfCRDPathMissing: true,
+	}
+	k8sClient = utils.StartTestEnv(schema, testEnv)
+	var err error
+	k8sClientset, err = kubernetes.NewForConfig(testEnv.Config)
+	Expect(err).NotTo(HaveOccurred(), "Error creating Kubernetes client")
+	// the specs bind the users to the ClusterRoles of the roles
+	Expect(ApplyRoleTemplates(context.Background(), k8sClient, roles.Default())).To(Succeed())
+
+	var ctx context.Context
+	ctx, stopInformers = context.WithCancel(context.Background())
+	factory := informers.NewSharedInformerFactory(k8sClientset, 0)
+	roleBindings = factory.Rbac().V1().RoleBindings().Lister()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+})
+
+var _ = AfterSuite(func() {
+	stopInformers()
+	utils.StopEnvTest(testEnv)
+})
+
+// Start a namespace cache, stopped when the current spec or container ends,
+// and wait for it to be synced
+func startTestNamespaceCache() *NamespaceCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	DeferCleanup(cancel)
+	namespaces := StartNamespaceCache(ctx, k8sClientset)
+	Expect(cache.WaitForCacheSync(ctx.Done(), namespaces.synced)).To(BeTrue())
+	return namespaces
+}
+
+// activeSignups reports the signups of all the users as complete
+type activeSignups struct{}
+
+func (activeSignups) Active(context.Context, string) (bool, error) {
+	return true, nil
+}
+
+// Create handlers working on the test cluster, the namespace cache and the
+// log of changes are left empty and all the signups are complete when not
+// given
+func newTestHandlers(opts Options) *Handlers {
+	opts.Client = k8sClient
+	opts.Clientset = k8sClientset
+	opts.RoleBindings = roleBindings
+	if opts.Namespaces == nil {
+		opts.Namespaces = &NamespaceCache{synced: func() bool { return true }}
+	}
+	if opts.Changes == nil {
+		opts.Changes = changes.NewLog(100)
+	}
+	if opts.Signups == nil {
+		opts.Signups = activeSignups{}
+	}
+	h, err := New(opts)
+	Expect(err).NotTo(HaveOccurred())
+	return h
+}
+
+// Read the last activity recorded in the annotations of the namespace
+func lastActivityOf(namespace string) (time.Time, error) {
+	ns := &core.Namespace{}
+	if err := k8sClient.Get(context.Background(), client.ObjectKey{Name: namespace}, ns); err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, ns.Annotations[lastActivityAnnotation])
+}
+
+// HTTPResponse is the response of a test server
+type HTTPResponse struct {
+	Body       string
+	StatusCode int
+}
+
+// Send a JSON request to a test server as the user with the given email
+func performJSONCall(method string, url string, email string, body string) *HTTPResponse {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	Expect(err).NotTo(HaveOccurred())
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if email != "" {
+		req.Header.Set("X-Email", email)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	Expect(err).NotTo(HaveOccurred())
+	return &HTTPResponse{Body: string(respBody), StatusCode: resp.StatusCode}
+}
+
+var _ = Describe("GetWorkspacesWithAccess querying for workspaces with access", func() {
+	var (
+		allNamespaces      []core.Namespace
+		expectedWorkspaces []crt.Workspace
+		e                  *echo.Echo
+		c                  echo.Context
+		gv                 string
+		h                  *Handlers
+	)
+
+	BeforeEach(func() {
+		h = newTestHandlers(Options{})
+		e = echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c = e.NewContext(req, rec)
+		c.Request().Header.Set("X-Email", "user@konflux.dev")
+	})
+
+	Context("When workspace test-tenant's namespaces has all the necessary permissions", func() {
+		namespaceNames := []string{"ws-test-tenant-1", "ws-test-tenant-2"}
+		BeforeEach(func() {
+			gv = crt.GroupVersion.String()
+			for _, name := range namespaceNames {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
+				allNamespaces = append(allNamespaces, ns)
+			}
+			expectedWorkspaces = []crt.Workspace{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "Workspace",
+						APIVersion: gv,
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ws-test-tenant-1",
+					},
+					Status: crt.WorkspaceStatus{
+						Namespaces: []crt.SpaceNamespace{
+							{
+								Name: "ws-test-tenant-1",
+								Type: "default",
+							},
+						},
+					},
+				},
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "Workspace",
+						APIVersion: gv,
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ws-test-tenant-2",
+					},
+					Status: crt.WorkspaceStatus{
+						Namespaces: []crt.SpaceNamespace{
+							{
+								Name: "ws-test-tenant-2",
+								Type: "default",
+							},
+						},
+					},
+				},
+			}
+		})
+		It("Should return a WorkspaceList with test-tenant workspace and both namespaces in it", func() {
+			mockNamespaceWithAccess := func(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error) {
+				return []core.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "ws-test-tenant-1",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "ws-test-tenant-2",
+						},
+					},
+				}, nil
+			}
+			h.namespacesWithAccess = mockNamespaceWithAccess
+			actualWorkspaces, err := h.getWorkspacesWithAccess(c, allNamespaces)
+			Expect(actualWorkspaces.Items).To(Equal(expectedWorkspaces))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
+		})
+	})
+
+	Context("When workspace with only ws-test-tenant-3 namespace has all the necessary permissions", func() {
+		namespaceNames := []string{"ws-test-tenant-3", "ws-test-tenant-4"}
+		BeforeEach(func() {
+			gv = crt.GroupVersion.String()
+			for _, name := range namespaceNames {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
+				allNamespaces = append(allNamespaces, ns)
+			}
+			expectedWorkspaces = []crt.Workspace{
+				{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "Workspace",
+						APIVersion: gv,
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ws-test-tenant-3",
+					},
+					Status: crt.WorkspaceStatus{
+						Namespaces: []crt.SpaceNamespace{
+							{
+								Name: "ws-test-tenant-3",
+								Type: "default",
+							},
+						},
+					},
+				},
+			}
+		})
+		It("Should return a WorkspaceList with test-tenant workspace and only test-tenant namespace in it", func() {
+			mockNamespaceWithAccess := func(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error) {
+				return []core.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "ws-test-tenant-3",
+						},
+					},
+				}, nil
+			}
+			h.namespacesWithAccess = mockNamespaceWithAccess
+			actualWorkspaces, err := h.getWorkspacesWithAccess(c, allNamespaces)
+			Expect(actualWorkspaces.Items).To(Equal(expectedWorkspaces))
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
+		})
+	})
+
+	Context("When namespaces are grouped into workspaces by label", func() {
+		It("Should return a Workspace per group with the typed namespaces", func() {
+			namespace := func(name string, labels map[string]string) core.Namespace {
+				return core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+			}
+			grouped := []core.Namespace{
+				namespace("grp-tenant-managed", map[string]string{
+					"konflux.ci/workspace": "grp-tenant", "konflux.ci/namespace-type": "managed",
+				}),
+				namespace("grp-solo", nil),
+				namespace("grp-tenant", map[string]string{"konflux.ci/workspace": "grp-tenant"}),
+				namespace("grp-other-dev", map[string]string{
+					"konflux.ci/workspace": "grp-other", "konflux.ci/namespace-type": "dev",
+				}),
+			}
+			mockNamespaceWithAccess := func(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error) {
+				return allNamespaces, nil
+			}
+			h.namespacesWithAccess = mockNamespaceWithAccess
+			actualWorkspaces, err := h.getWorkspacesWithAccess(c, grouped)
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
+
+			namespacesByWorkspace := map[string][]crt.SpaceNamespace{}
+			var names []string
+			for _, ws := range actualWorkspaces.Items {
+				names = append(names, ws.Name)
+				namespacesByWorkspace[ws.Name] = ws.Status.Namespaces
+			}
+			Expect(names).To(Equal([]string{"grp-other", "grp-solo", "grp-tenant"}))
+			Expect(namespacesByWorkspace).To(Equal(map[string][]crt.SpaceNamespace{
+				"grp-other": {{Name: "grp-other-dev", Type: "dev"}},
+				"grp-solo":  {{Name: "grp-solo", Type: "default"}},
+				"grp-tenant": {
+					{Name: "grp-tenant", Type: "default"},
+					{Name: "grp-tenant-managed", Type: "managed"},
+				},
+			}))
+		})
+	})
+
+	Context("When no workspaces has all the necessary permissions", func() {
+		namespaceNames := []string{"ws-test-tenant-5", "ws-test-tenant-6"}
+		BeforeEach(func() {
+			for _, name := range namespaceNames {
+				ns, err := utils.CreateNamespace(k8sClient, name)
+				Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error while creating the namespace %s: %v", name, err))
+				allNamespaces = append(allNamespaces, ns)
+			}
+		})
+		It("Should return a empty WorkspaceList", func() {
+			mockNamespaceWithAccess := func(c echo.Context, allNamespaces []core.Namespace) ([]core.Namespace, error) {
+				return []core.Namespace{}, nil
+			}
+			h.namespacesWithAccess = mockNamespaceWithAccess
+			actualWorkspaces, err := h.getWorkspacesWithAccess(c, allNamespaces)
+			Expect(actualWorkspaces.Items).To(BeEmpty())
+			Expect(err).NotTo(HaveOccurred(), "Unexpected error testing GetWorkspacesWithAccess")
+		})
+	})
+})
+var _ = Describe("Workspace owner and role", Ordered, func() {
+	var h *Handlers
+	owner := "owner-user@konflux.dev"
+
+	BeforeAll(func() {
+		h = newTestHandlers(Options{})
+		for _, name := range []string{"owner-test-annotated", "owner-test-bound", "owner-test-unowned"} {
+			_, err := utils.CreateNamespace(k8sClient, name)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		for _, ns := range []string{"owner-test-annotated", "owner-test-bound"} {
+			binding := &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "owner-admin", Namespace: ns},
+				Subjects:   []rbacv1.Subject{{Kind: "User", Name: owner, APIGroup: rbacv1.GroupName}},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "admin", APIGroup: rbacv1.GroupName},
+			}
+			Expect(k8sClient.Create(context.Background(), binding)).To(Succeed())
+		}
+		utils.BindClusterRole(k8sClient, "owner-test-admin", "owner-test-bound", owner, "konflux-admin-user-actions")
+		utils.CreateRole(k8sClient, "owner-test-bound", "owner-test-viewer", []string{"list", "watch"})
+		utils.CreateRoleBinding(k8sClient, "owner-test-viewer", "owner-test-bound", "viewer-user@konflux.dev", "owner-test-viewer")
+		utils.CreateRole(k8sClient, "owner-test-bound", "owner-test-contributor",
+			[]string{"list", "watch", "create", "update", "patch"})
+		utils.CreateRoleBinding(k8sClient, "owner-test-contributor", "owner-test-bound",
+			"contributor-user@konflux.dev", "owner-test-contributor")
+	})
+
+	It("takes the owner from the namespace annotation", func() {
+		ns := core.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "owner-test-annotated",
+			Annotations: map[string]string{"konflux.ci/owner": "annotated-user@konflux.dev"},
+		}}
+		Expect(h.workspaceOwner(ns)).To(Equal("annotated-user@konflux.dev"))
+	})
+
+	It("falls back to the admin RoleBinding", func() {
+		ns := core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "owner-test-bound"}}
+		// the RoleBindings are read from the cache
+		Eventually(func() (string, error) {
+			return h.workspaceOwner(ns)
+		}).Should(Equal(owner))
+	})
+
+	It("has no owner without admin RoleBinding", func() {
+		ns := core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "owner-test-unowned"}}
+		Expect(h.workspaceOwner(ns)).To(BeEmpty())
+	})
+
+	It("takes the role the caller is bound to without checking it", func() {
+		bound := newTestHandlers(Options{})
+		bound.accessCheck = func(context.Context, string, string, accessrules.Check) (bool, error) {
+			Fail("the access of the admin should not be checked")
+			return false, nil
+		}
+		Eventually(func() (string, error) {
+			return bound.workspaceRole(context.Background(), owner, "owner-test-bound")
+		}).Should(Equal("admin"))
+	})
+
+	DescribeTable("computing the role of the caller",
+		func(user string, expected string) {
+			Expect(h.workspaceRole(context.Background(), user, "owner-test-bound")).To(Equal(expected))
+		},
+		Entry("for an admin", owner, "admin"),
+		Entry("for a contributor", "contributor-user@konflux.dev", "contributor"),
+		Entry("for a viewer", "viewer-user@konflux.dev", "viewer"),
+		Entry("for a user without access", "nobody@konflux.dev", ""),
+	)
+})
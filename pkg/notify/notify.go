@@ -0,0 +1,108 @@
+// Package notify delivers messages to the users of the workspace manager
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier delivers a message to the user with the given email
+type Notifier interface {
+	Notify(ctx context.Context, to string, subject string, body string) error
+}
+
+// SMTP sends the messages as emails through an SMTP server
+type SMTP struct {
+	// host:port of the SMTP server
+	Addr string
+	// Address the emails are sent from
+	From string
+	// Authentication against the server, none when nil
+	Auth smtp.Auth
+}
+
+func (s *SMTP) Notify(ctx context.Context, to string, subject string, body string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{to}, message(s.From, to, subject, body))
+}
+
+// Build the content of an email, header fields can't contain line breaks
+func message(from string, to string, subject string, body string) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(msg, "To: %s\r\n", header.Replace(to))
+	fmt.Fprintf(msg, "Subject: %s\r\n", header.Replace(subject))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+// Message is a message delivered to the outbox
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// Outbox appends the messages to a local file, one JSON object per line,
+// instead of sending them. It stands in for SMTP in development and tests.
+type Outbox struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (o *Outbox) Notify(ctx context.Context, to string, subject string, body string) error {
+	line, err := json.Marshal(&Message{To: to, Subject: subject, Body: body, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f, err := os.OpenFile(o.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadOutbox returns the messages delivered to the outbox file at path,
+// oldest first
+func ReadOutbox(path string) ([]Message, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var messages []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		message := Message{}
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, scanner.Err()
+}
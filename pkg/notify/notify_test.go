@@ -0,0 +1,40 @@
+package notify_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/notify"
+)
+
+func TestNotify(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Notify Suite")
+}
+
+var _ = Describe("Outbox", func() {
+	It("reads back the messages in the order they were delivered", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "outbox")
+		outbox := &notify.Outbox{Path: path}
+		Expect(outbox.Notify(context.Background(), "first@konflux.dev", "Hello", "line 1\nline 2")).To(Succeed())
+		Expect(outbox.Notify(context.Background(), "second@konflux.dev", "Bye", "")).To(Succeed())
+
+		messages, err := notify.ReadOutbox(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[0].To).To(Equal("first@konflux.dev"))
+		Expect(messages[0].Subject).To(Equal("Hello"))
+		Expect(messages[0].Body).To(Equal("line 1\nline 2"))
+		Expect(messages[1].To).To(Equal("second@konflux.dev"))
+	})
+
+	It("reads an outbox nothing was delivered to as empty", func() {
+		messages, err := notify.ReadOutbox(filepath.Join(GinkgoT().TempDir(), "outbox"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages).To(BeEmpty())
+	})
+})
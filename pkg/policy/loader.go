@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader evaluates the policy read from a file and picks up the changes
+// made to the file without restarting the service
+type Loader struct {
+	path    string
+	current atomic.Pointer[Policy]
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// Load reads the policy from the file at path
+func Load(path string) (*Loader, error) {
+	l := &Loader{path: path}
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Evaluate decides whether the user with the given email is allowed by the
+// last successfully loaded policy
+func (l *Loader) Evaluate(email string) Decision {
+	return l.current.Load().Evaluate(email)
+}
+
+// Reload reads the policy file again if it changed since the last load.
+// The current policy is kept if the file can't be read or is invalid.
+func (l *Loader) Reload() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return false, err
+	}
+	if l.current.Load() != nil && info.ModTime().Equal(l.modTime) && info.Size() == l.size {
+		return false, nil
+	}
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return false, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return false, err
+	}
+	l.current.Store(p)
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+	return true, nil
+}
+
+// Watch checks the policy file for changes every interval until ctx is done.
+// Reload errors are passed to onError.
+func (l *Loader) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.Reload(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package policy
+
+import (
+	"net/http"
+
+	"github.com/konflux-ci/workspace-manager/pkg/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware rejects the requests of the users denied by the policy with
+// a 403 status carrying the reason of the denial. Unauthenticated requests
+// are left for the handlers to deal with.
+func Middleware(ev Evaluator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			email := auth.Email(c)
+			if email == "" {
+				return next(c)
+			}
+			if decision := ev.Evaluate(email); !decision.Allowed {
+				return echo.NewHTTPError(http.StatusForbidden, decision.Reason)
+			}
+			return next(c)
+		}
+	}
+}
+
+// AllowAll is the Evaluator used when no policy is configured
+type AllowAll struct{}
+
+func (AllowAll) Evaluate(string) Decision {
+	return Decision{Allowed: true}
+}
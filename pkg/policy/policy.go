@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Rules matches users by their email
+type Rules struct {
+	// Exact email addresses
+	Emails []string `json:"emails,omitempty"`
+	// Email domains, matching the part of the address after the @
+	Domains []string `json:"domains,omitempty"`
+	// Regular expressions matched against the whole address
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// Config is the content of the policy file.
+// Users matching a deny rule are always denied. When allow rules are
+// configured, users must match one of them to be allowed.
+type Config struct {
+	Allow Rules `json:"allow,omitempty"`
+	Deny  Rules `json:"deny,omitempty"`
+}
+
+// Decision is the result of evaluating the policy for a user
+type Decision struct {
+	Allowed bool
+	// Explanation of the decision, meant to be shown to the user
+	Reason string
+}
+
+// Evaluator decides whether a user is allowed to access the service
+type Evaluator interface {
+	Evaluate(email string) Decision
+}
+
+type matcher struct {
+	emails   map[string]bool
+	domains  map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// Policy is a compiled policy Config
+type Policy struct {
+	allow matcher
+	deny  matcher
+}
+
+// Parse compiles the YAML or JSON encoded policy Config
+func Parse(data []byte) (*Policy, error) {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	return New(config)
+}
+
+// New compiles a policy Config
+func New(config Config) (*Policy, error) {
+	allow, err := newMatcher(config.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := newMatcher(config.Deny)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{allow: allow, deny: deny}, nil
+}
+
+func newMatcher(rules Rules) (matcher, error) {
+	m := matcher{emails: map[string]bool{}, domains: map[string]bool{}}
+	for _, email := range rules.Emails {
+		m.emails[strings.ToLower(email)] = true
+	}
+	for _, domain := range rules.Domains {
+		m.domains[strings.ToLower(domain)] = true
+	}
+	for _, pattern := range rules.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return matcher{}, fmt.Errorf("invalid policy pattern %q: %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+func (m *matcher) empty() bool {
+	return len(m.emails) == 0 && len(m.domains) == 0 && len(m.patterns) == 0
+}
+
+// Return a description of the rule matching the email, or an empty string
+func (m *matcher) match(email string) string {
+	email = strings.ToLower(email)
+	if m.emails[email] {
+		return "address " + email
+	}
+	if _, domain, found := strings.Cut(email, "@"); found && m.domains[domain] {
+		return "domain " + domain
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(email) {
+			return "pattern " + re.String()
+		}
+	}
+	return ""
+}
+
+// Evaluate decides whether the user with the given email is allowed
+func (p *Policy) Evaluate(email string) Decision {
+	if rule := p.deny.match(email); rule != "" {
+		return Decision{Reason: fmt.Sprintf("user %s is denied by %s", email, rule)}
+	}
+	if p.allow.empty() {
+		return Decision{Allowed: true}
+	}
+	if rule := p.allow.match(email); rule != "" {
+		return Decision{Allowed: true, Reason: fmt.Sprintf("user %s is allowed by %s", email, rule)}
+	}
+	return Decision{Reason: fmt.Sprintf("user %s is not in the allowed users", email)}
+}
@@ -0,0 +1,116 @@
+package policy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/policy"
+)
+
+func TestPolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Access policy Suite")
+}
+
+var _ = DescribeTable("Evaluating a policy",
+	func(config string, email string, allowed bool, reason string) {
+		p, err := policy.Parse([]byte(config))
+		Expect(err).NotTo(HaveOccurred())
+		decision := p.Evaluate(email)
+		Expect(decision.Allowed).To(Equal(allowed))
+		Expect(decision.Reason).To(Equal(reason))
+	},
+	Entry("allows everyone without rules", ``, "user@konflux.dev", true, ""),
+	Entry(
+		"denies an address",
+		"deny:\n  emails: [Bad@Konflux.dev]",
+		"bad@konflux.dev", false, "user bad@konflux.dev is denied by address bad@konflux.dev",
+	),
+	Entry(
+		"denies a domain",
+		"deny:\n  domains: [spam.dev]",
+		"user@spam.dev", false, "user user@spam.dev is denied by domain spam.dev",
+	),
+	Entry(
+		"denies a pattern",
+		"deny:\n  patterns: ['^test-.*']",
+		"test-1@konflux.dev", false, "user test-1@konflux.dev is denied by pattern ^test-.*",
+	),
+	Entry(
+		"lets deny rules win over allow rules",
+		"allow:\n  domains: [konflux.dev]\ndeny:\n  emails: [bad@konflux.dev]",
+		"bad@konflux.dev", false, "user bad@konflux.dev is denied by address bad@konflux.dev",
+	),
+	Entry(
+		"allows users matching the allow rules",
+		"allow:\n  domains: [konflux.dev]",
+		"user@konflux.dev", true, "user user@konflux.dev is allowed by domain konflux.dev",
+	),
+	Entry(
+		"denies users not matching the allow rules",
+		"allow:\n  domains: [konflux.dev]",
+		"user@other.dev", false, "user user@other.dev is not in the allowed users",
+	),
+	Entry(
+		"does not match subdomains",
+		"allow:\n  domains: [konflux.dev]",
+		"user@sub.konflux.dev", false, "user user@sub.konflux.dev is not in the allowed users",
+	),
+)
+
+var _ = Describe("Parsing a policy", func() {
+	It("rejects invalid patterns", func() {
+		_, err := policy.Parse([]byte("deny:\n  patterns: ['(']"))
+		Expect(err).To(MatchError(ContainSubstring("invalid policy pattern")))
+	})
+
+	It("rejects unknown fields", func() {
+		_, err := policy.Parse([]byte("denied:\n  emails: [user@konflux.dev]"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Loading a policy file", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "policy.yaml")
+		Expect(os.WriteFile(path, []byte("deny:\n  domains: [spam.dev]"), 0o600)).To(Succeed())
+	})
+
+	It("picks up the changes made to the file", func() {
+		loader, err := policy.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loader.Evaluate("user@spam.dev").Allowed).To(BeFalse())
+		Expect(loader.Evaluate("user@other.dev").Allowed).To(BeTrue())
+
+		Expect(os.WriteFile(path, []byte("deny:\n  domains: [other.dev]"), 0o600)).To(Succeed())
+		Expect(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))).To(Succeed())
+		reloaded, err := loader.Reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).To(BeTrue())
+		Expect(loader.Evaluate("user@spam.dev").Allowed).To(BeTrue())
+		Expect(loader.Evaluate("user@other.dev").Allowed).To(BeFalse())
+	})
+
+	It("keeps the previous policy when the file becomes invalid", func() {
+		loader, err := policy.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(path, []byte("deny:\n  patterns: ['(']"), 0o600)).To(Succeed())
+		Expect(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))).To(Succeed())
+		_, err = loader.Reload()
+		Expect(err).To(HaveOccurred())
+		Expect(loader.Evaluate("user@spam.dev").Allowed).To(BeFalse())
+	})
+
+	It("fails when the file does not exist", func() {
+		_, err := policy.Load(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,145 @@
+// Package rbac evaluates the RBAC rules of the cluster in process, from
+// informer caches, instead of sending a SubjectAccessReview per check
+package rbac
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Name of the index of the ClusterRoleBindings by user subject
+const userSubjectIndex = "userSubject"
+
+// Authorizer decides whether a user is allowed to perform an action the
+// way the RBAC authorizer of the API server does, for the bindings
+// granted to the user itself. Bindings to groups are ignored since the
+// groups of the users are not known.
+type Authorizer struct {
+	roles               rbaclisters.RoleLister
+	clusterRoles        rbaclisters.ClusterRoleLister
+	roleBindings        rbaclisters.RoleBindingLister
+	clusterRoleBindings cache.Indexer
+	synced              []cache.InformerSynced
+}
+
+// NewAuthorizer creates an Authorizer watching the RBAC objects through
+// the informers of factory. The factory has to be started afterwards.
+func NewAuthorizer(factory informers.SharedInformerFactory) (*Authorizer, error) {
+	rbac := factory.Rbac().V1()
+	clusterRoleBindings := rbac.ClusterRoleBindings().Informer()
+	err := clusterRoleBindings.AddIndexers(cache.Indexers{userSubjectIndex: indexByUserSubject})
+	if err != nil {
+		return nil, err
+	}
+	a := &Authorizer{
+		roles:               rbac.Roles().Lister(),
+		clusterRoles:        rbac.ClusterRoles().Lister(),
+		roleBindings:        rbac.RoleBindings().Lister(),
+		clusterRoleBindings: clusterRoleBindings.GetIndexer(),
+	}
+	a.synced = []cache.InformerSynced{
+		rbac.Roles().Informer().HasSynced,
+		rbac.ClusterRoles().Informer().HasSynced,
+		rbac.RoleBindings().Informer().HasSynced,
+		clusterRoleBindings.HasSynced,
+	}
+	return a, nil
+}
+
+// WaitForCacheSync waits until the informers have synced, it returns false
+// if ctx is done first
+func (a *Authorizer) WaitForCacheSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), a.synced...)
+}
+
+// Allowed reports whether the user is allowed to perform the action
+// described by attrs
+func (a *Authorizer) Allowed(user string, attrs authorizationv1.ResourceAttributes) (bool, error) {
+	clusterBindings, err := a.clusterRoleBindings.ByIndex(userSubjectIndex, user)
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range clusterBindings {
+		binding := obj.(*rbacv1.ClusterRoleBinding)
+		allowed, err := a.roleRefAllows("", binding.RoleRef, attrs)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	if attrs.Namespace == "" {
+		return false, nil
+	}
+	bindings, err := a.roleBindings.RoleBindings(attrs.Namespace).List(everything)
+	if err != nil {
+		return false, err
+	}
+	for _, binding := range bindings {
+		if !bindsUser(binding.Subjects, binding.Namespace, user) {
+			continue
+		}
+		allowed, err := a.roleRefAllows(binding.Namespace, binding.RoleRef, attrs)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// Check whether the rules of the referenced role allow the action. A missing
+// role allows nothing, as for the API server.
+func (a *Authorizer) roleRefAllows(
+	namespace string, ref rbacv1.RoleRef, attrs authorizationv1.ResourceAttributes,
+) (bool, error) {
+	var rules []rbacv1.PolicyRule
+	switch ref.Kind {
+	case "ClusterRole":
+		role, err := a.clusterRoles.Get(ref.Name)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		rules = role.Rules
+	case "Role":
+		if namespace == "" {
+			return false, nil
+		}
+		role, err := a.roles.Roles(namespace).Get(ref.Name)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		rules = role.Rules
+	default:
+		return false, fmt.Errorf("unsupported role kind %q", ref.Kind)
+	}
+	for i := range rules {
+		if ruleAllows(&rules[i], attrs) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func indexByUserSubject(obj interface{}) ([]string, error) {
+	binding, ok := obj.(*rbacv1.ClusterRoleBinding)
+	if !ok {
+		return nil, nil
+	}
+	var users []string
+	for _, subject := range binding.Subjects {
+		if user := subjectUser(subject, ""); user != "" {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
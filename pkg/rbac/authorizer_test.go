@@ -0,0 +1,133 @@
+package rbac_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/rbac"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRBAC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RBAC authorizer Suite")
+}
+
+func userSubject(name string) rbacv1.Subject {
+	return rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: name}
+}
+
+func listApplications(namespace string) authorizationv1.ResourceAttributes {
+	return authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "list",
+		Group:     "appstudio.redhat.com",
+		Resource:  "applications",
+	}
+}
+
+var _ = Describe("Authorizer", func() {
+	var authorizer *rbac.Authorizer
+
+	BeforeEach(func() {
+		objects := []runtime.Object{
+			&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "viewer", Namespace: "tenant"},
+				Rules: []rbacv1.PolicyRule{{
+					Verbs:     []string{"get", "list", "watch"},
+					APIGroups: []string{"appstudio.redhat.com"},
+					Resources: []string{"applications", "components"},
+				}},
+			},
+			&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "named", Namespace: "tenant"},
+				Rules: []rbacv1.PolicyRule{{
+					Verbs:         []string{"list"},
+					APIGroups:     []string{"appstudio.redhat.com"},
+					Resources:     []string{"applications"},
+					ResourceNames: []string{"app"},
+				}},
+			},
+			&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "everything"},
+				Rules: []rbacv1.PolicyRule{{
+					Verbs:     []string{"*"},
+					APIGroups: []string{"*"},
+					Resources: []string{"*"},
+				}},
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "viewers", Namespace: "tenant"},
+				Subjects: []rbacv1.Subject{
+					userSubject("viewer@konflux.dev"),
+					{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "viewers"},
+					{Kind: rbacv1.ServiceAccountKind, Name: "pipeline"},
+				},
+				RoleRef: rbacv1.RoleRef{Kind: "Role", APIGroup: rbacv1.GroupName, Name: "viewer"},
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "named", Namespace: "tenant"},
+				Subjects:   []rbacv1.Subject{userSubject("named@konflux.dev")},
+				RoleRef:    rbacv1.RoleRef{Kind: "Role", APIGroup: rbacv1.GroupName, Name: "named"},
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "tenant"},
+				Subjects:   []rbacv1.Subject{userSubject("admin@konflux.dev")},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "everything"},
+			},
+			&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "tenant"},
+				Subjects:   []rbacv1.Subject{userSubject("missing@konflux.dev")},
+				RoleRef:    rbacv1.RoleRef{Kind: "Role", APIGroup: rbacv1.GroupName, Name: "missing"},
+			},
+			&rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-admins"},
+				Subjects:   []rbacv1.Subject{userSubject("cluster-admin@konflux.dev")},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "everything"},
+			},
+		}
+		factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(objects...), 0)
+		var err error
+		authorizer, err = rbac.NewAuthorizer(factory)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		factory.Start(ctx.Done())
+		Expect(authorizer.WaitForCacheSync(ctx)).To(BeTrue())
+	})
+
+	DescribeTable("deciding on an action",
+		func(user string, attrs authorizationv1.ResourceAttributes, expected bool) {
+			allowed, err := authorizer.Allowed(user, attrs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(Equal(expected))
+		},
+		Entry("allows a user bound to a role", "viewer@konflux.dev", listApplications("tenant"), true),
+		Entry("denies a verb the role doesn't grant", "viewer@konflux.dev",
+			authorizationv1.ResourceAttributes{
+				Namespace: "tenant", Verb: "delete", Group: "appstudio.redhat.com", Resource: "applications",
+			},
+			false,
+		),
+		Entry("denies a user in another namespace", "viewer@konflux.dev", listApplications("other"), false),
+		Entry("allows a service account bound without namespace",
+			"system:serviceaccount:tenant:pipeline", listApplications("tenant"), true,
+		),
+		Entry("ignores the group subjects", "viewers", listApplications("tenant"), false),
+		Entry("denies listing with a rule restricted to resource names",
+			"named@konflux.dev", listApplications("tenant"), false,
+		),
+		Entry("allows a user bound to a cluster role in the namespace", "admin@konflux.dev", listApplications("tenant"), true),
+		Entry("denies a user bound to a missing role", "missing@konflux.dev", listApplications("tenant"), false),
+		Entry("allows a user bound cluster wide", "cluster-admin@konflux.dev", listApplications("other"), true),
+		Entry("denies an unknown user", "unknown@konflux.dev", listApplications("tenant"), false),
+	)
+})
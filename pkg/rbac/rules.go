@@ -0,0 +1,82 @@
+package rbac
+
+import (
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var everything = labels.Everything()
+
+// Prefix of the names under which the service accounts authenticate
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// Check whether one of the subjects is the user. bindingNamespace is used
+// for the ServiceAccount subjects without namespace.
+func bindsUser(subjects []rbacv1.Subject, bindingNamespace string, user string) bool {
+	for _, subject := range subjects {
+		if subjectUser(subject, bindingNamespace) == user {
+			return true
+		}
+	}
+	return false
+}
+
+// Name of the user a subject stands for, empty for group subjects
+func subjectUser(subject rbacv1.Subject, bindingNamespace string) string {
+	switch subject.Kind {
+	case rbacv1.UserKind:
+		return subject.Name
+	case rbacv1.ServiceAccountKind:
+		namespace := subject.Namespace
+		if namespace == "" {
+			namespace = bindingNamespace
+		}
+		if namespace == "" {
+			return ""
+		}
+		return serviceAccountUserPrefix + namespace + ":" + subject.Name
+	}
+	return ""
+}
+
+// Check whether a resource rule allows the action, following the matching
+// of the RBAC authorizer of the API server
+func ruleAllows(rule *rbacv1.PolicyRule, attrs authorizationv1.ResourceAttributes) bool {
+	resource := attrs.Resource
+	if attrs.Subresource != "" {
+		resource += "/" + attrs.Subresource
+	}
+	return matches(rule.Verbs, attrs.Verb) &&
+		matches(rule.APIGroups, attrs.Group) &&
+		resourceMatches(rule.Resources, resource, attrs.Subresource) &&
+		(len(rule.ResourceNames) == 0 || (attrs.Name != "" && contains(rule.ResourceNames, attrs.Name)))
+}
+
+func matches(values []string, value string) bool {
+	return contains(values, rbacv1.VerbAll) || contains(values, value)
+}
+
+// Resources match exactly, on "*", or on "*/subresource" for subresources
+func resourceMatches(values []string, resource string, subresource string) bool {
+	for _, value := range values {
+		switch {
+		case value == rbacv1.ResourceAll, value == resource:
+			return true
+		case subresource != "" && strings.HasPrefix(value, "*/") && value[2:] == subresource:
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
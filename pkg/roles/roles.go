@@ -0,0 +1,224 @@
+// Package roles maps the permissions of a user in a workspace onto named roles
+package roles
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Names of the default roles, from the least to the most privileged
+const (
+	Viewer      = "viewer"
+	Contributor = "contributor"
+	Maintainer  = "maintainer"
+	Admin       = "admin"
+)
+
+// Definition is a role as given in the roles file
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Template of the ClusterRole granting the role to the members of the
+	// workspaces. A user holds the role when allowed all its actions.
+	ClusterRole rbacv1.ClusterRole `json:"clusterRole"`
+}
+
+// Config is the content of the roles file
+type Config struct {
+	// The roles from the least to the most privileged
+	Roles []Definition `json:"roles"`
+}
+
+// Role is a named set of actions a user has to be allowed to perform in a
+// workspace to hold the role
+type Role struct {
+	Name        string
+	Description string
+	// ClusterRole the members holding the role are bound to
+	ClusterRole *rbacv1.ClusterRole
+	Rules       *accessrules.RuleSet
+}
+
+// Catalog lists the roles from the least to the most privileged
+type Catalog []Role
+
+// Default returns the viewer, contributor, maintainer and admin roles, each
+// allowing the actions of the previous one. They are granted by the
+// konflux-<role>-user-actions ClusterRoles.
+func Default() Catalog {
+	viewer := []rbacv1.PolicyRule{{
+		APIGroups: []string{"appstudio.redhat.com"},
+		Resources: []string{"applications", "components"},
+		Verbs:     []string{"list", "watch"},
+	}}
+	contributor := append(viewer, rbacv1.PolicyRule{
+		APIGroups: []string{"appstudio.redhat.com"},
+		Resources: []string{"applications", "components"},
+		Verbs:     []string{"create", "update", "patch"},
+	})
+	maintainer := append(contributor, rbacv1.PolicyRule{
+		APIGroups: []string{"appstudio.redhat.com"},
+		Resources: []string{"applications", "components"},
+		Verbs:     []string{"delete"},
+	}, rbacv1.PolicyRule{
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+		Verbs:     []string{"create", "update", "delete"},
+	})
+	admin := append(maintainer, rbacv1.PolicyRule{
+		APIGroups: []string{rbacv1.GroupName},
+		Resources: []string{"rolebindings"},
+		Verbs:     []string{"create", "delete"},
+	})
+	catalog, err := New(Config{Roles: []Definition{
+		defaultRole(Viewer, "Views the applications and components", viewer),
+		defaultRole(Contributor, "Creates and updates the applications and components", contributor),
+		defaultRole(Maintainer, "Deletes the applications and components and manages the secrets", maintainer),
+		defaultRole(Admin, "Manages the access to the workspace", admin),
+	}})
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+func defaultRole(name string, description string, rules []rbacv1.PolicyRule) Definition {
+	return Definition{
+		Name:        name,
+		Description: description,
+		ClusterRole: rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "konflux-" + name + "-user-actions"},
+			// copied so that the rules of the roles don't share their backing arrays
+			Rules: append([]rbacv1.PolicyRule{}, rules...),
+		},
+	}
+}
+
+// Load reads the roles from the file at path
+func Load(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse compiles the YAML or JSON encoded roles Config
+func Parse(data []byte) (Catalog, error) {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid roles: %w", err)
+	}
+	return New(config)
+}
+
+// New compiles a roles Config. The rules of the ClusterRoles have to name
+// their API groups, resources and verbs, without wildcards nor resource
+// names, so that the actions they allow can be checked.
+func New(config Config) (Catalog, error) {
+	if len(config.Roles) == 0 {
+		return nil, fmt.Errorf("invalid roles: no role given")
+	}
+	catalog := Catalog{}
+	for _, definition := range config.Roles {
+		if definition.Name == "" {
+			return nil, fmt.Errorf("invalid roles: a role has no name")
+		}
+		if catalog.Has(definition.Name) {
+			return nil, fmt.Errorf("invalid roles: role %s is defined twice", definition.Name)
+		}
+		if definition.ClusterRole.Name == "" {
+			return nil, fmt.Errorf("invalid role %s: the ClusterRole has no name", definition.Name)
+		}
+		var rules []accessrules.Rule
+		for i, rule := range definition.ClusterRole.Rules {
+			if err := checkable(rule); err != nil {
+				return nil, fmt.Errorf("invalid role %s: rule %d %w", definition.Name, i, err)
+			}
+			for _, group := range rule.APIGroups {
+				rules = append(rules, accessrules.Rule{Group: group, Resources: rule.Resources, Verbs: rule.Verbs})
+			}
+		}
+		set, err := accessrules.New(accessrules.Config{Match: accessrules.MatchAll, Rules: rules})
+		if err != nil {
+			return nil, fmt.Errorf("invalid role %s: %w", definition.Name, err)
+		}
+		clusterRole := definition.ClusterRole.DeepCopy()
+		clusterRole.Kind = "ClusterRole"
+		clusterRole.APIVersion = rbacv1.SchemeGroupVersion.String()
+		catalog = append(catalog, Role{
+			Name:        definition.Name,
+			Description: definition.Description,
+			ClusterRole: clusterRole,
+			Rules:       set,
+		})
+	}
+	return catalog, nil
+}
+
+// Check that the actions a rule allows can be checked one by one
+func checkable(rule rbacv1.PolicyRule) error {
+	switch {
+	case len(rule.NonResourceURLs) > 0:
+		return fmt.Errorf("has non resource URLs")
+	case len(rule.ResourceNames) > 0:
+		return fmt.Errorf("has resource names")
+	case len(rule.APIGroups) == 0 || len(rule.Resources) == 0 || len(rule.Verbs) == 0:
+		return fmt.Errorf("needs API groups, resources and verbs")
+	}
+	for _, values := range [][]string{rule.APIGroups, rule.Resources, rule.Verbs} {
+		for _, value := range values {
+			if value == "*" {
+				return fmt.Errorf("has a wildcard")
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns the name of the most privileged role whose actions are all
+// allowed, allowed reporting whether the user is allowed a single action.
+// An empty name is returned if the user holds none of the roles.
+func (c Catalog) Resolve(allowed func(accessrules.Check) (bool, error)) (string, error) {
+	return c.ResolveFrom("", allowed)
+}
+
+// ResolveFrom is Resolve for a user known to hold the role named held: only
+// the more privileged roles are checked, held being returned if the user holds
+// none of them. All the roles are checked when held is empty or unknown.
+func (c Catalog) ResolveFrom(held string, allowed func(accessrules.Check) (bool, error)) (string, error) {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].Name == held {
+			return held, nil
+		}
+		ok, err := c[i].Rules.Evaluate(allowed)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return c[i].Name, nil
+		}
+	}
+	return "", nil
+}
+
+// Get returns the role with the given name, false if the catalog has none
+func (c Catalog) Get(name string) (Role, bool) {
+	for _, role := range c {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// Has reports whether the catalog has a role with the given name
+func (c Catalog) Has(name string) bool {
+	_, found := c.Get(name)
+	return found
+}
@@ -0,0 +1,165 @@
+package roles_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/accessrules"
+	"github.com/konflux-ci/workspace-manager/pkg/roles"
+)
+
+func TestRoles(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Workspace roles Suite")
+}
+
+// allowing the checks of the given resources and verbs
+func allowing(verbsByResource map[string][]string) func(accessrules.Check) (bool, error) {
+	return func(check accessrules.Check) (bool, error) {
+		for _, verb := range verbsByResource[check.Resource] {
+			if verb == check.Verb {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+var _ = DescribeTable("Resolving the role of a user",
+	func(verbsByResource map[string][]string, expected string) {
+		Expect(roles.Default().Resolve(allowing(verbsByResource))).To(Equal(expected))
+	},
+	Entry("without permissions", map[string][]string{}, ""),
+	Entry("with read access", map[string][]string{
+		"applications": {"list", "watch"},
+		"components":   {"list", "watch"},
+	}, roles.Viewer),
+	Entry("with write access to the applications only", map[string][]string{
+		"applications": {"list", "watch", "create", "update", "patch"},
+		"components":   {"list", "watch"},
+	}, roles.Viewer),
+	Entry("with write access", map[string][]string{
+		"applications": {"list", "watch", "create", "update", "patch"},
+		"components":   {"list", "watch", "create", "update", "patch"},
+	}, roles.Contributor),
+	Entry("with delete and secrets access", map[string][]string{
+		"applications": {"list", "watch", "create", "update", "patch", "delete"},
+		"components":   {"list", "watch", "create", "update", "patch", "delete"},
+		"secrets":      {"create", "update", "delete"},
+	}, roles.Maintainer),
+	Entry("with access to the RoleBindings only", map[string][]string{
+		"rolebindings": {"create", "delete"},
+	}, ""),
+	Entry("with access to the RoleBindings", map[string][]string{
+		"applications": {"list", "watch", "create", "update", "patch", "delete"},
+		"components":   {"list", "watch", "create", "update", "patch", "delete"},
+		"secrets":      {"create", "update", "delete"},
+		"rolebindings": {"create", "delete"},
+	}, roles.Admin),
+)
+
+var _ = Describe("Resolve", func() {
+	It("returns the errors of the checks", func() {
+		_, err := roles.Default().Resolve(func(accessrules.Check) (bool, error) {
+			return false, errors.New("unavailable")
+		})
+		Expect(err).To(MatchError("unavailable"))
+	})
+})
+
+var _ = Describe("ResolveFrom", func() {
+	It("only checks the roles more privileged than the one held", func() {
+		checks := 0
+		role, err := roles.Default().ResolveFrom(roles.Maintainer, func(accessrules.Check) (bool, error) {
+			checks++
+			return false, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(role).To(Equal(roles.Maintainer))
+		// the admin role is denied on its first check
+		Expect(checks).To(Equal(1))
+	})
+
+	It("finds a more privileged role", func() {
+		Expect(roles.Default().ResolveFrom(roles.Viewer, allowing(map[string][]string{
+			"applications": {"list", "watch", "create", "update", "patch"},
+			"components":   {"list", "watch", "create", "update", "patch"},
+		}))).To(Equal(roles.Contributor))
+	})
+})
+
+var _ = Describe("Get", func() {
+	It("finds a role by name", func() {
+		role, found := roles.Default().Get(roles.Admin)
+		Expect(found).To(BeTrue())
+		Expect(role.Name).To(Equal(roles.Admin))
+	})
+
+	It("reports a missing role", func() {
+		_, found := roles.Default().Get("owner")
+		Expect(found).To(BeFalse())
+	})
+})
+
+var _ = Describe("Parse", func() {
+	It("reads the roles and their ClusterRoles", func() {
+		catalog, err := roles.Parse([]byte(`
+roles:
+- name: reader
+  description: Reads the secrets
+  clusterRole:
+    metadata:
+      name: secret-reader
+    rules:
+    - apiGroups: [""]
+      resources: [secrets]
+      verbs: [get, list]
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog).To(HaveLen(1))
+		Expect(catalog[0].Name).To(Equal("reader"))
+		Expect(catalog[0].Description).To(Equal("Reads the secrets"))
+		Expect(catalog[0].ClusterRole.Name).To(Equal("secret-reader"))
+		Expect(catalog[0].ClusterRole.Kind).To(Equal("ClusterRole"))
+		Expect(catalog[0].Rules.Checks()).To(Equal([]accessrules.Check{
+			{Group: "", Resource: "secrets", Verb: "get"},
+			{Group: "", Resource: "secrets", Verb: "list"},
+		}))
+	})
+
+	DescribeTable("rejecting invalid roles",
+		func(data string, expected string) {
+			_, err := roles.Parse([]byte(data))
+			Expect(err).To(MatchError(ContainSubstring(expected)))
+		},
+		Entry("without roles", `roles: []`, "no role given"),
+		Entry("with an unknown field", `roles: [{name: reader, rules: []}]`, "unknown field"),
+		Entry("without ClusterRole name",
+			`roles: [{name: reader, clusterRole: {rules: [{apiGroups: [""], resources: [secrets], verbs: [get]}]}}]`,
+			"the ClusterRole has no name",
+		),
+		Entry("defined twice", `
+roles:
+- {name: reader, clusterRole: {metadata: {name: a}, rules: [{apiGroups: [""], resources: [secrets], verbs: [get]}]}}
+- {name: reader, clusterRole: {metadata: {name: b}, rules: [{apiGroups: [""], resources: [secrets], verbs: [get]}]}}
+`, "defined twice"),
+		Entry("with a wildcard", `
+roles:
+- name: reader
+  clusterRole:
+    metadata: {name: a}
+    rules: [{apiGroups: ["*"], resources: [secrets], verbs: [get]}]
+`, "wildcard"),
+		Entry("with resource names", `
+roles:
+- name: reader
+  clusterRole:
+    metadata: {name: a}
+    rules: [{apiGroups: [""], resources: [secrets], resourceNames: [token], verbs: [get]}]
+`, "resource names"),
+		Entry("without rules", `roles: [{name: reader, clusterRole: {metadata: {name: a}}}]`, "no rule given"),
+	)
+})
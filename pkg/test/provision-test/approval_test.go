@@ -0,0 +1,187 @@
+package provision_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Allow the given user to manage the signups
+func createSignupAdmin(email string) {
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: "signup-admin", Namespace: "workspace-manager"},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"workspaces.konflux.ci"},
+				Resources: []string{"usersignups"},
+				Verbs:     []string{"list", "update"},
+			},
+		},
+	}
+	err := k8sClient.Create(context.Background(), role)
+	if !apierrors.IsAlreadyExists(err) {
+		Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'Role' resource: %v", err))
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "signup-admin", Namespace: "workspace-manager"},
+		Subjects: []rbacv1.Subject{
+			{Kind: "User", Name: email, APIGroup: "rbac.authorization.k8s.io"},
+		},
+		RoleRef: rbacv1.RoleRef{Kind: "Role", Name: "signup-admin", APIGroup: "rbac.authorization.k8s.io"},
+	}
+	err = k8sClient.Create(context.Background(), binding)
+	if !apierrors.IsAlreadyExists(err) {
+		Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'RoleBinding' resource: %v", err))
+	}
+}
+
+var _ = Describe("Signup approval", func() {
+	admin := "signup-admin@konflux.dev"
+
+	BeforeEach(func() {
+		createSignupAdmin(admin)
+	})
+
+	Context("When a user outside the auto-approved domains signs up", func() {
+		email := "approve-me@external.dev"
+
+		It("holds the signup until an administrator approves it", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+
+			signup := utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeFalse())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.PendingApproval))
+
+			err = k8sClient.Get(context.Background(), types.NamespacedName{Name: "approve-me-tenant"}, &core.Namespace{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the namespace should not be created before approval")
+
+			response, err = utils.PerformRequest("GET", "/api/v1/admin/signups", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			pending := &v1alpha1.SignupApprovalList{}
+			Expect(json.Unmarshal([]byte(utils.ReadBody(response)), pending)).To(Succeed())
+			Expect(pending.Items).To(ContainElement(HaveField("Email", email)))
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/approve", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			signup = utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.SignedUp))
+			Expect(signup.SignupStatus.Conditions).To(ContainElement(And(
+				HaveField("Type", v1alpha1.SignupApproved),
+				HaveField("Status", metav1.ConditionTrue),
+			)))
+		})
+	})
+
+	Context("When the namespace of the user was provisioned for another user meanwhile", func() {
+		email := "approve-late@external.dev"
+
+		It("assigns a new username to the user", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(utils.GetSignup(email).CompliantUsername).To(Equal("approve-late"))
+			// as another user getting the same username concurrently would
+			ns := &core.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "approve-late-tenant",
+					Labels:      map[string]string{"konflux.ci/type": "user"},
+					Annotations: map[string]string{"konflux.ci/owner": "approve-late@other.dev"},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/approve", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			signup := utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.CompliantUsername).To(Equal("approve-late-2"))
+			Expect(signup.DefaultUserNamespace).To(Equal("approve-late-2-tenant"))
+		})
+	})
+
+	Context("When the user is deactivated", func() {
+		email := "approve-deactivated@external.dev"
+
+		It("refuses to approve the signup", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/deactivate", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/approve", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusConflict))
+
+			Expect(utils.GetSignup(email).SignupStatus.Reason).To(Equal(v1alpha1.Deactivated))
+			err = k8sClient.Get(
+				context.Background(), types.NamespacedName{Name: "approve-deactivated-tenant"}, &core.Namespace{},
+			)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the namespace should not be provisioned")
+		})
+	})
+
+	Context("When an administrator rejects a signup", func() {
+		email := "reject-me@external.dev"
+
+		It("reports the rejection and refuses further signups", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/reject?reason=spam", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			signup := utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeFalse())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.Rejected))
+			Expect(signup.SignupStatus.Message).To(Equal("the signup was rejected: spam"))
+
+			response, err = signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("When a user that is not an administrator calls the admin endpoints", func() {
+		It("responds with forbidden", func() {
+			response, err := utils.PerformRequest("GET", "/api/v1/admin/signups", "approve-me@external.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/someone@external.dev/approve", "someone@external.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("When approving an unknown signup", func() {
+		It("responds with not found", func() {
+			response, err := utils.PerformRequest("POST", "/api/v1/admin/signups/nobody@external.dev/approve", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,184 @@
+package provision_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func bindUsers(nsName string, bindingName string, users ...string) {
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: bindingName, Namespace: nsName},
+		RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "view", APIGroup: "rbac.authorization.k8s.io"},
+	}
+	for _, user := range users {
+		binding.Subjects = append(binding.Subjects, rbacv1.Subject{
+			Kind: "User", Name: user, APIGroup: "rbac.authorization.k8s.io",
+		})
+	}
+	Expect(k8sClient.Create(context.Background(), binding)).To(Succeed())
+}
+
+func getRoleBinding(nsName string, bindingName string) (*rbacv1.RoleBinding, error) {
+	binding := &rbacv1.RoleBinding{}
+	err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: nsName, Name: bindingName}, binding)
+	return binding, err
+}
+
+var _ = Describe("Signup deactivation", func() {
+	admin := "signup-admin@konflux.dev"
+
+	BeforeEach(func() {
+		createSignupAdmin(admin)
+	})
+
+	Context("When a user deletes its signup", Ordered, func() {
+		email := "offboarded@konflux.dev"
+
+		It("revokes the access of the user and retains its namespace", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			shared := &core.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "offboarding-shared",
+					Labels: map[string]string{"konflux.ci/type": "user"},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), shared)).To(Succeed())
+			workspace := &core.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "offboarded-workspace",
+					Labels:      map[string]string{"konflux.ci/type": "user"},
+					Annotations: map[string]string{"konflux.ci/owner": email},
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), workspace)).To(Succeed())
+			bindUsers("offboarding-shared", "both", email, "stays@konflux.dev")
+			bindUsers("offboarding-shared", "alone", email)
+
+			response, err = signupRequest("DELETE", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			_, err = getRoleBinding("offboarded-tenant", "konflux-owner")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the owner binding should be removed")
+			_, err = getRoleBinding("offboarded-tenant", "owner-view")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the tier binding of the owner should be removed")
+			_, err = getRoleBinding("offboarding-shared", "alone")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "bindings left without subjects should be removed")
+			both, err := getRoleBinding("offboarding-shared", "both")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(both.Subjects).To(ConsistOf(HaveField("Name", "stays@konflux.dev")))
+
+			ns := &core.Namespace{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "offboarded-tenant"}, ns)).To(Succeed())
+			Expect(ns.DeletionTimestamp).To(BeNil())
+			Expect(ns.Annotations).To(HaveKey("konflux.ci/delete-after"))
+
+			signup := utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeFalse())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.Deactivated))
+		})
+
+		It("reactivates the user when it signs up again", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			_, err = getRoleBinding("offboarded-tenant", "konflux-owner")
+			Expect(err).NotTo(HaveOccurred())
+			tierBinding, err := getRoleBinding("offboarded-tenant", "owner-view")
+			Expect(err).NotTo(HaveOccurred(), "the tier should be applied again")
+			Expect(tierBinding.Subjects).To(ConsistOf(HaveField("Name", email)))
+			owner, err := getRoleBinding("offboarded-workspace", "konflux-owner")
+			Expect(err).NotTo(HaveOccurred(), "the access to all the owned namespaces should be restored")
+			Expect(owner.Subjects).To(ConsistOf(HaveField("Name", email)))
+			_, err = getRoleBinding("offboarded-workspace", "owner-view")
+			Expect(err).NotTo(HaveOccurred())
+			ns := &core.Namespace{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "offboarded-tenant"}, ns)).To(Succeed())
+			Expect(ns.Annotations).NotTo(HaveKey("konflux.ci/delete-after"))
+
+			signup := utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.SignedUp))
+		})
+	})
+
+	Context("When a user deletes its signup with another casing of its email", func() {
+		email := "casing@konflux.dev"
+
+		It("revokes the access to the namespaces it owns and lets it sign up again", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response, err = signupRequest("DELETE", "Casing@Konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			_, err = getRoleBinding("casing-tenant", "konflux-owner")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the owner binding should be removed")
+			ns := &core.Namespace{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "casing-tenant"}, ns)).To(Succeed())
+			Expect(ns.Annotations).To(HaveKey("konflux.ci/delete-after"))
+
+			response, err = signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			_, err = getRoleBinding("casing-tenant", "konflux-owner")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When an administrator deactivates a user", func() {
+		email := "deactivated@konflux.dev"
+
+		It("prevents the user from signing up again until reactivated", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/deactivate", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(utils.GetSignup(email).SignupStatus.Reason).To(Equal(v1alpha1.Deactivated))
+
+			response, err = signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+			response, err = utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/reactivate", admin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			signup := utils.GetSignup(email)
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.SignedUp))
+		})
+
+		It("refuses callers that are not administrators", func() {
+			response, err := utils.PerformRequest("POST", "/api/v1/admin/signups/"+email+"/deactivate", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("When deactivating a user that never signed up", func() {
+		It("responds with not found", func() {
+			response, err := signupRequest("DELETE", "never@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
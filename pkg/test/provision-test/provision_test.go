@@ -2,16 +2,24 @@ package provision_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
 	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,9 +39,34 @@ var serverCancelFunc context.CancelFunc
 var _ = BeforeSuite(func() {
 	schema := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(schema))
-	testEnv = &envtest.Environment{BinaryAssetsDirectory: "../../../bin/k8s/1.29.0-linux-amd64/"}
+	utilruntime.Must(v1alpha1.AddToScheme(schema))
+	testEnv = &envtest.Environment{
+		BinaryAssetsDirectory: "../../../bin/k8s/1.29.0-linux-amd64/",
+		CRDDirectoryPaths:     []string{"../../../config/crd/bases"},
+		ErrorIfCRDPathMissing: true,
+	}
 	k8sClient = utils.StartTestEnv(schema, testEnv)
-	serverProcess, serverCancelFunc = utils.CreateWorkspaceManagerServer("../../../cmd/main.go", nil, "")
+	signupNamespace := &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "workspace-manager"}}
+	Expect(k8sClient.Create(context.Background(), signupNamespace)).To(Succeed())
+	policyFile := filepath.Join(GinkgoT().TempDir(), "policy.yaml")
+	Expect(os.WriteFile(policyFile, []byte("deny:\n  domains: [banned.dev]\n"), 0o600)).To(Succeed())
+	tiersFile := filepath.Join(GinkgoT().TempDir(), "tiers.yaml")
+	tiers := "tiers:\n- name: base\n  limitRanges:\n  - metadata: {name: defaults}\n" +
+		"    spec: {limits: [{type: Container, default: {memory: 512Mi}}]}\n" +
+		"  roleBindings:\n  - metadata: {name: owner-view}\n" +
+		"    subjects: [{kind: User, name: '${OWNER}', apiGroup: rbac.authorization.k8s.io}]\n" +
+		"    roleRef: {kind: ClusterRole, name: view, apiGroup: rbac.authorization.k8s.io}\n"
+	Expect(os.WriteFile(tiersFile, []byte(tiers), 0o600)).To(Succeed())
+	serverProcess, serverCancelFunc = utils.CreateWorkspaceManagerServer(
+		"../../../cmd/main.go",
+		[]string{
+			"SIGNUP_APPROVAL_REQUIRED=true",
+			"SIGNUP_AUTO_APPROVE_DOMAINS=konflux.dev",
+			"ACCESS_POLICY_FILE=" + policyFile,
+			"TIERS_FILE=" + tiersFile,
+		},
+		"",
+	)
 	utils.WaitForWorkspaceManagerServerToServe()
 })
 
@@ -42,27 +75,205 @@ var _ = AfterSuite(func() {
 	utils.StopEnvTest(testEnv)
 })
 
-var _ = Describe("simple test", func() {
-	endpoint := "http://localhost:5000/api/v1/signup"
-	httpClient := &http.Client{}
+func signupRequest(method string, email string) (*http.Response, error) {
+	return utils.PerformRequest(method, "/api/v1/signup", email)
+}
+
+var _ = Describe("Signup provisions a namespace", func() {
+	Context("When a new user signs up", func() {
+		email := "provision-user@konflux.dev"
+		nsName := "provision-user-tenant"
+
+		It("creates a user namespace with the user bound as admin", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			ns := &core.Namespace{}
+			err = k8sClient.Get(context.Background(), types.NamespacedName{Name: nsName}, ns)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Namespace %s was not created: %v", nsName, err))
+			Expect(ns.Labels).To(HaveKeyWithValue("konflux.ci/type", "user"))
+
+			binding := &rbacv1.RoleBinding{}
+			err = k8sClient.Get(context.Background(), types.NamespacedName{Namespace: nsName, Name: "konflux-owner"}, binding)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("RoleBinding was not created: %v", err))
+			Expect(binding.RoleRef.Kind).To(Equal("ClusterRole"))
+			Expect(binding.RoleRef.Name).To(Equal("admin"))
+			Expect(binding.Subjects).To(ConsistOf(rbacv1.Subject{
+				Kind:     "User",
+				Name:     email,
+				APIGroup: "rbac.authorization.k8s.io",
+			}))
+
+			roleBinding := &rbacv1.RoleBinding{}
+			key := types.NamespacedName{Namespace: nsName, Name: "konflux-owner-role"}
+			Expect(k8sClient.Get(context.Background(), key, roleBinding)).To(Succeed())
+			Expect(roleBinding.RoleRef.Name).To(Equal("konflux-admin-user-actions"))
+			Expect(roleBinding.Subjects).To(ConsistOf(HaveField("Name", email)))
+		})
 
-	Context("simple test context", func() {
-		It("simple spec", func() {
-			request, err := http.NewRequest("GET", endpoint, nil)
+		It("keeps the owner bindings when the user signs up again", func() {
+			response, err := signupRequest("POST", email)
 			Expect(err).NotTo(HaveOccurred())
-			Eventually(
-				func() (int, error) {
-					response, err := httpClient.Do(request)
-					if err != nil {
-						fmt.Println(err.Error())
-						return 0, err
-					}
-					return response.StatusCode, nil
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			key := types.NamespacedName{Namespace: nsName, Name: "konflux-owner"}
+			binding := &rbacv1.RoleBinding{}
+			Expect(k8sClient.Get(context.Background(), key, binding)).To(Succeed())
 
+			response, err = signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			kept := &rbacv1.RoleBinding{}
+			Expect(k8sClient.Get(context.Background(), key, kept)).To(Succeed())
+			Expect(kept.UID).To(Equal(binding.UID))
+		})
+
+		It("applies the default tier to the namespace", func() {
+			ns := &core.Namespace{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: nsName}, ns)).To(Succeed())
+			Expect(ns.Labels).To(HaveKeyWithValue("konflux.ci/tier", "base"))
+			limits := &core.LimitRange{}
+			err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: nsName, Name: "defaults"}, limits)
+			Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("LimitRange was not created: %v", err))
+			Expect(limits.Labels).To(HaveKeyWithValue("konflux.ci/tier", "base"))
+		})
+
+		It("records the signup in a UserSignup", func() {
+			signups := &v1alpha1.UserSignupList{}
+			Expect(k8sClient.List(context.Background(), signups, client.InNamespace("workspace-manager"))).To(Succeed())
+			var record *v1alpha1.UserSignup
+			for i := range signups.Items {
+				if signups.Items[i].Spec.Email == email {
+					record = &signups.Items[i]
+				}
+			}
+			Expect(record).NotTo(BeNil(), fmt.Sprintf("No UserSignup was created for %s", email))
+			Expect(record.Spec.CompliantUsername).To(Equal("provision-user"))
+			Expect(record.Spec.State).To(Equal(v1alpha1.ApprovalApproved))
+			Expect(record.Labels).To(HaveKeyWithValue("konflux.ci/signup-state", v1alpha1.ApprovalApproved))
+		})
+
+		It("reports the user as signed up", func() {
+			response, err := signupRequest("POST", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response, err = signupRequest("GET", email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			signup := &v1alpha1.Signup{}
+			Expect(json.Unmarshal([]byte(utils.ReadBody(response)), signup)).To(Succeed())
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.SignedUp))
+			Expect(signup.SignupStatus.Message).To(Equal("namespace provision-user-tenant is ready"))
+			Expect(signup.SignupStatus.CreationTimestamp).NotTo(BeNil())
+			Expect(signup.SignupStatus.Conditions).To(HaveLen(3))
+			for _, condition := range signup.SignupStatus.Conditions {
+				Expect(condition.Type).To(BeElementOf(v1alpha1.SignupApproved, v1alpha1.SignupProvisioned, v1alpha1.SignupReady))
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			}
+		})
+
+		It("reports a namespace being deleted as deactivated", func() {
+			response, err := signupRequest("POST", "leaving@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			// envtest runs no namespace controller so the namespace stays in Terminating
+			ns := &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "leaving-tenant"}}
+			Expect(k8sClient.Delete(context.Background(), ns)).To(Succeed())
+
+			response, err = signupRequest("GET", "leaving@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			signup := &v1alpha1.Signup{}
+			Expect(json.Unmarshal([]byte(utils.ReadBody(response)), signup)).To(Succeed())
+			Expect(signup.SignupStatus.Ready).To(BeFalse())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.Deactivated))
+		})
+	})
+
+	Context("When the namespace of the user belongs to someone else", func() {
+		It("provisions a namespace with a suffixed name", func() {
+			ns := &core.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "taken-tenant",
+					Labels:      map[string]string{"konflux.ci/type": "user"},
+					Annotations: map[string]string{"konflux.ci/owner": "taken@other.dev"},
 				},
-				10,
-				1,
-			).Should(Equal(http.StatusOK))
+			}
+			Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
+
+			response, err := signupRequest("POST", "taken@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			signup := utils.GetSignup("taken@konflux.dev")
+			Expect(signup.SignupStatus.Ready).To(BeTrue())
+			Expect(signup.CompliantUsername).To(Equal("taken-2"))
+			Expect(signup.DefaultUserNamespace).To(Equal("taken-2-tenant"))
+		})
+	})
+
+	Context("When another user already got the username", func() {
+		It("assigns the next free username", func() {
+			response, err := signupRequest("POST", "same.name@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(utils.GetSignup("same.name@konflux.dev").CompliantUsername).To(Equal("same-name"))
+
+			// still waiting for approval, the username is assigned nonetheless
+			response, err = signupRequest("POST", "same_name@external.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(utils.GetSignup("same_name@external.dev").CompliantUsername).To(Equal("same-name-2"))
+		})
+	})
+
+	Context("When the username is reserved", func() {
+		It("prefixes the username", func() {
+			response, err := signupRequest("POST", "kube-admin@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			signup := utils.GetSignup("kube-admin@konflux.dev")
+			Expect(signup.CompliantUsername).To(Equal("user-kube-admin"))
+			Expect(signup.DefaultUserNamespace).To(Equal("user-kube-admin-tenant"))
+		})
+	})
+
+	Context("When the user did not sign up", func() {
+		It("responds with not found", func() {
+			response, err := signupRequest("GET", "unknown@konflux.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("When the access policy denies the user", func() {
+		It("refuses the signup and reports the user as banned", func() {
+			response, err := signupRequest("POST", "user@banned.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(utils.ReadBody(response)).To(Equal(`{"message":"user user@banned.dev is denied by domain banned.dev"}`))
+
+			signup := utils.GetSignup("user@banned.dev")
+			Expect(signup.SignupStatus.Ready).To(BeFalse())
+			Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.Banned))
+			Expect(signup.SignupStatus.Message).To(Equal("user user@banned.dev is denied by domain banned.dev"))
+
+			response, err = utils.PerformRequest("GET", "/workspaces", "user@banned.dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("When the request carries no user identity", func() {
+		It("responds with unauthorized", func() {
+			response, err := signupRequest("POST", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
 		})
 	})
 })
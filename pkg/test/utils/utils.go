@@ -2,13 +2,20 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
 	. "github.com/onsi/gomega"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -164,3 +171,108 @@ func StopWorkspaceManagerServer(cmd *exec.Cmd, serverCancelFunc context.CancelFu
 		Expect(err).Should(BeAssignableToTypeOf(&exec.ExitError{}))
 	}
 }
+
+// Send a request to the workspace-manager server as the user with the given
+// email, anonymously when it is empty
+func PerformRequest(method string, path string, email string) (*http.Response, error) {
+	request, err := http.NewRequest(method, "http://localhost:5000"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if email != "" {
+		request.Header.Add("X-Email", email)
+	}
+	return http.DefaultClient.Do(request)
+}
+
+// Read and close the body of the response
+func ReadBody(response *http.Response) string {
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	Expect(err).NotTo(HaveOccurred())
+	return strings.TrimSpace(string(body))
+}
+
+// Get the signup of the user from the workspace-manager server
+func GetSignup(email string) *v1alpha1.Signup {
+	response, err := PerformRequest("GET", "/api/v1/signup", email)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(response.StatusCode).To(Equal(http.StatusOK))
+	signup := &v1alpha1.Signup{}
+	Expect(json.Unmarshal([]byte(ReadBody(response)), signup)).To(Succeed())
+	return signup
+}
+
+// Create a user namespace
+func CreateNamespace(k8sClient client.Client, name string) (core.Namespace, error) {
+	namespaced := &core.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"konflux.ci/type":             "user",
+				"kubernetes.io/metadata.name": name,
+			},
+		},
+	}
+	if err := k8sClient.Create(context.Background(), namespaced); err != nil {
+		return core.Namespace{}, fmt.Errorf("Error creating 'Namespace' resource: %v", err)
+	}
+	return *namespaced, nil
+}
+
+// Create a Role granting the verbs on the applications and components
+func CreateRole(k8sClient client.Client, nsName string, roleName string, verbs []string) {
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleName,
+			Namespace: nsName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"appstudio.redhat.com"},
+				Resources: []string{"applications", "components"},
+				Verbs:     verbs,
+			},
+		},
+	}
+	err := k8sClient.Create(context.Background(), role)
+	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'Role' resource: %v", err))
+}
+
+// Bind the user to a Role in a namespace
+func CreateRoleBinding(k8sClient client.Client, bindingName string, nsName string, userName string, roleName string) {
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bindingName,
+			Namespace: nsName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:     "User",
+				Name:     userName,
+				APIGroup: "rbac.authorization.k8s.io",
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "Role",
+			Name:     roleName,
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}
+	err := k8sClient.Create(context.Background(), roleBinding)
+	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'roleBinding' resource: %v", err))
+}
+
+// Bind the user to a ClusterRole in a namespace
+func BindClusterRole(k8sClient client.Client, bindingName string, nsName string, userName string, clusterRoleName string) {
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bindingName,
+			Namespace: nsName,
+		},
+		Subjects: []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: userName}},
+		RoleRef:  rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: clusterRoleName},
+	}
+	err := k8sClient.Create(context.Background(), roleBinding)
+	Expect(err).NotTo(HaveOccurred(), fmt.Sprintf("Error creating 'roleBinding' resource: %v", err))
+}
@@ -0,0 +1,217 @@
+package verification_test
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/api/v1alpha1"
+	"github.com/konflux-ci/workspace-manager/pkg/notify"
+	"github.com/konflux-ci/workspace-manager/pkg/test/utils"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+func TestVerification(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Signup verification Suite")
+}
+
+var k8sClient client.Client
+var testEnv *envtest.Environment
+var serverProcess *exec.Cmd
+var serverCancelFunc context.CancelFunc
+var outboxFile string
+
+var _ = BeforeSuite(func() {
+	schema := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(schema))
+	utilruntime.Must(v1alpha1.AddToScheme(schema))
+	testEnv = &envtest.Environment{
+		BinaryAssetsDirectory: "../../../bin/k8s/1.29.0-linux-amd64/",
+		CRDDirectoryPaths:     []string{"../../../config/crd/bases"},
+		ErrorIfCRDPathMissing: true,
+	}
+	k8sClient = utils.StartTestEnv(schema, testEnv)
+	signupNamespace := &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "workspace-manager"}}
+	Expect(k8sClient.Create(context.Background(), signupNamespace)).To(Succeed())
+	outboxFile = filepath.Join(GinkgoT().TempDir(), "outbox")
+	serverProcess, serverCancelFunc = utils.CreateWorkspaceManagerServer(
+		"../../../cmd/main.go",
+		[]string{
+			"SIGNUP_VERIFICATION_REQUIRED=true",
+			"VERIFICATION_NOTIFIER=outbox",
+			"VERIFICATION_OUTBOX_FILE=" + outboxFile,
+			"VERIFICATION_RESEND_INTERVAL=1h",
+			"VERIFICATION_MAX_ATTEMPTS=2",
+		},
+		"",
+	)
+	utils.WaitForWorkspaceManagerServerToServe()
+})
+
+var _ = AfterSuite(func() {
+	utils.StopWorkspaceManagerServer(serverProcess, serverCancelFunc)
+	utils.StopEnvTest(testEnv)
+})
+
+var codePattern = regexp.MustCompile(`verification code is (\d{6})`)
+
+// Read the last verification code delivered to the user
+func lastCode(email string) string {
+	messages, err := notify.ReadOutbox(outboxFile)
+	Expect(err).NotTo(HaveOccurred())
+	code := ""
+	for _, message := range messages {
+		if message.To == email {
+			match := codePattern.FindStringSubmatch(message.Body)
+			Expect(match).To(HaveLen(2), "unexpected message body: "+message.Body)
+			code = match[1]
+		}
+	}
+	Expect(code).NotTo(BeEmpty(), "no verification code was delivered to "+email)
+	return code
+}
+
+var _ = Describe("Signup verification", Ordered, func() {
+	email := "verify-user@konflux.dev"
+	nsName := "verify-user-tenant"
+
+	It("holds the signup until the email is verified", func() {
+		response, err := utils.PerformRequest("POST", "/api/v1/signup", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+
+		signup := utils.GetSignup(email)
+		Expect(signup.SignupStatus.Ready).To(BeFalse())
+		Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.VerificationRequired))
+		err = k8sClient.Get(context.Background(), types.NamespacedName{Name: nsName}, &core.Namespace{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the namespace was provisioned before the verification")
+	})
+
+	It("sends a verification code", func() {
+		response, err := utils.PerformRequest("POST", "/api/v1/signup/verification", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(lastCode(email)).To(HaveLen(6))
+	})
+
+	It("throttles the codes sent", func() {
+		response, err := utils.PerformRequest("POST", "/api/v1/signup/verification", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(response.Header.Get("Retry-After")).NotTo(BeEmpty())
+	})
+
+	It("rejects a wrong code", func() {
+		wrongCode := "000000"
+		if lastCode(email) == wrongCode {
+			wrongCode = "000001"
+		}
+		response, err := utils.PerformRequest("POST", "/api/v1/signup/verification/"+wrongCode, email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		Expect(utils.GetSignup(email).SignupStatus.Reason).To(Equal(v1alpha1.VerificationRequired))
+	})
+
+	It("provisions the namespace once the code is verified", func() {
+		response, err := utils.PerformRequest("POST", "/api/v1/signup/verification/"+lastCode(email), email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		signup := utils.GetSignup(email)
+		Expect(signup.SignupStatus.Ready).To(BeTrue())
+		Expect(signup.SignupStatus.Reason).To(Equal(v1alpha1.SignedUp))
+		Expect(signup.DefaultUserNamespace).To(Equal(nsName))
+		Expect(signup.SignupStatus.Conditions).To(ContainElement(And(
+			HaveField("Type", v1alpha1.SignupVerified),
+			HaveField("Status", metav1.ConditionTrue),
+		)))
+	})
+
+	It("does not send codes to verified users", func() {
+		response, err := utils.PerformRequest("POST", "/api/v1/signup/verification", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+var _ = Describe("Signup verification attempts", func() {
+	It("invalidates the code after too many wrong attempts", func() {
+		email := "guess-user@konflux.dev"
+		response, err := utils.PerformRequest("POST", "/api/v1/signup", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+		response, err = utils.PerformRequest("POST", "/api/v1/signup/verification", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		code := lastCode(email)
+		wrongCode := "000000"
+		if code == wrongCode {
+			wrongCode = "000001"
+		}
+
+		for i := 0; i < 2; i++ {
+			response, err = utils.PerformRequest("POST", "/api/v1/signup/verification/"+wrongCode, email)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		}
+		response, err = utils.PerformRequest("POST", "/api/v1/signup/verification/"+code, email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(utils.GetSignup(email).SignupStatus.Reason).To(Equal(v1alpha1.VerificationRequired))
+	})
+
+	It("counts the wrong attempts made concurrently", func() {
+		email := "concurrent-guess-user@konflux.dev"
+		response, err := utils.PerformRequest("POST", "/api/v1/signup", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+		response, err = utils.PerformRequest("POST", "/api/v1/signup/verification", email)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		wrongCode := "000000"
+		if lastCode(email) == wrongCode {
+			wrongCode = "000001"
+		}
+
+		statuses := make(chan int, 10)
+		for i := 0; i < cap(statuses); i++ {
+			go func() {
+				defer GinkgoRecover()
+				response, err := utils.PerformRequest("POST", "/api/v1/signup/verification/"+wrongCode, email)
+				Expect(err).NotTo(HaveOccurred())
+				statuses <- response.StatusCode
+			}()
+		}
+		rejected := 0
+		for i := 0; i < cap(statuses); i++ {
+			status := <-statuses
+			Expect(status).To(BeElementOf(http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests))
+			if status == http.StatusForbidden {
+				rejected++
+			}
+		}
+		// the attempts losing the race on the record are answered with a conflict
+		Expect(rejected).To(BeNumerically("<=", 2))
+	})
+
+	It("requires the user to sign up first", func() {
+		response, err := utils.PerformRequest("POST", "/api/v1/signup/verification", "unknown-user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
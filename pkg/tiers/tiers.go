@@ -0,0 +1,296 @@
+// Package tiers defines the templates of the objects created in the user
+// namespaces, such as their quotas, limits and network policies, the way
+// the NSTemplateTiers of the toolchain do
+package tiers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Label holding the tier of a namespace, and of the objects created
+	// in the namespace from the templates of the tier
+	TierLabel = "konflux.ci/tier"
+	// Name of the tier used when no tier is configured
+	DefaultName = "base"
+)
+
+// Definition is a tier as given in the tiers file. The templates are
+// created in the namespaces of the tier, their namespace is set to the
+// namespace and the ${NAMESPACE} and ${OWNER} parameters are replaced by
+// the name of the namespace and the email of its owner.
+type Definition struct {
+	Name            string                       `json:"name"`
+	Description     string                       `json:"description,omitempty"`
+	ResourceQuotas  []core.ResourceQuota         `json:"resourceQuotas,omitempty"`
+	LimitRanges     []core.LimitRange            `json:"limitRanges,omitempty"`
+	NetworkPolicies []networkingv1.NetworkPolicy `json:"networkPolicies,omitempty"`
+	ServiceAccounts []core.ServiceAccount        `json:"serviceAccounts,omitempty"`
+	RoleBindings    []rbacv1.RoleBinding         `json:"roleBindings,omitempty"`
+}
+
+// Config is the content of the tiers file
+type Config struct {
+	// Name of the tier of the new namespaces, the first tier when not set
+	Default string       `json:"default,omitempty"`
+	Tiers   []Definition `json:"tiers"`
+}
+
+// Tier is a named set of object templates
+type Tier struct {
+	Name        string
+	Description string
+	templates   []client.Object
+}
+
+// Catalog is a compiled tiers Config
+type Catalog struct {
+	defaultTier *Tier
+	tiers       []*Tier
+}
+
+// The lists of the kinds of objects a tier may hold, for finding the
+// objects created from the templates of the previous tier of a namespace
+func templateLists() []client.ObjectList {
+	return []client.ObjectList{
+		&core.ResourceQuotaList{},
+		&core.LimitRangeList{},
+		&networkingv1.NetworkPolicyList{},
+		&core.ServiceAccountList{},
+		&rbacv1.RoleBindingList{},
+	}
+}
+
+// Default returns the catalog used when no file is configured, made of the
+// base tier that has no templates
+func Default() *Catalog {
+	catalog, _ := New(Config{Tiers: []Definition{{Name: DefaultName}}})
+	return catalog
+}
+
+// Load reads the tiers from the file at path
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse compiles the YAML or JSON encoded tiers Config
+func Parse(data []byte) (*Catalog, error) {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid tiers: %w", err)
+	}
+	return New(config)
+}
+
+// New compiles a tiers Config
+func New(config Config) (*Catalog, error) {
+	if len(config.Tiers) == 0 {
+		return nil, fmt.Errorf("invalid tiers: no tier given")
+	}
+	c := &Catalog{}
+	for _, definition := range config.Tiers {
+		if definition.Name == "" {
+			return nil, fmt.Errorf("invalid tiers: a tier has no name")
+		}
+		if _, found := c.Get(definition.Name); found {
+			return nil, fmt.Errorf("invalid tiers: tier %s is defined twice", definition.Name)
+		}
+		tier := &Tier{Name: definition.Name, Description: definition.Description}
+		tier.templates = append(tier.templates, pointers(definition.ResourceQuotas)...)
+		tier.templates = append(tier.templates, pointers(definition.LimitRanges)...)
+		tier.templates = append(tier.templates, pointers(definition.NetworkPolicies)...)
+		tier.templates = append(tier.templates, pointers(definition.ServiceAccounts)...)
+		tier.templates = append(tier.templates, pointers(definition.RoleBindings)...)
+		seen := map[string]bool{}
+		for _, template := range tier.templates {
+			key := objectKey(template)
+			switch {
+			case template.GetName() == "":
+				return nil, fmt.Errorf("invalid tier %s: a %s has no name", tier.Name, kindOf(template))
+			case template.GetNamespace() != "":
+				return nil, fmt.Errorf("invalid tier %s: %s can't set its namespace", tier.Name, key)
+			case seen[key]:
+				return nil, fmt.Errorf("invalid tier %s: %s is defined twice", tier.Name, key)
+			}
+			seen[key] = true
+		}
+		c.tiers = append(c.tiers, tier)
+	}
+	defaultName := config.Default
+	if defaultName == "" {
+		defaultName = c.tiers[0].Name
+	}
+	defaultTier, found := c.Get(defaultName)
+	if !found {
+		return nil, fmt.Errorf("invalid tiers: the default tier %s is not defined", defaultName)
+	}
+	c.defaultTier = defaultTier
+	return c, nil
+}
+
+// Pointers to copies of the objects, the type parameter being the type of
+// the objects and P the pointer type implementing client.Object
+func pointers[T any, P interface {
+	*T
+	client.Object
+}](objects []T) []client.Object {
+	var result []client.Object
+	for i := range objects {
+		var copied P = new(T)
+		*copied = objects[i]
+		result = append(result, copied)
+	}
+	return result
+}
+
+// Name of the Go type of an object, such as ResourceQuota
+func kindOf(obj client.Object) string {
+	return reflect.TypeOf(obj).Elem().Name()
+}
+
+// Identify an object of a namespace by its kind and name
+func objectKey(obj client.Object) string {
+	return kindOf(obj) + "/" + obj.GetName()
+}
+
+// DefaultTier returns the tier of the new namespaces
+func (c *Catalog) DefaultTier() *Tier {
+	return c.defaultTier
+}
+
+// Get returns the tier with the given name, false if the catalog has none
+func (c *Catalog) Get(name string) (*Tier, bool) {
+	for _, tier := range c.tiers {
+		if tier.Name == name {
+			return tier, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of the tiers, in the order they were defined
+func (c *Catalog) Names() []string {
+	var names []string
+	for _, tier := range c.tiers {
+		names = append(names, tier.Name)
+	}
+	return names
+}
+
+// Objects renders the templates of the tier for the namespace owned by the
+// user with the given email. The objects are labelled with the tier.
+func (t *Tier) Objects(namespace string, owner string) ([]client.Object, error) {
+	parameters := strings.NewReplacer("${NAMESPACE}", jsonEscape(namespace), "${OWNER}", jsonEscape(owner))
+	var objects []client.Object
+	for _, template := range t.templates {
+		data, err := json.Marshal(template)
+		if err != nil {
+			return nil, err
+		}
+		// the values are replaced in the JSON strings of the document,
+		// escaped so that they can't end the strings
+		data = []byte(parameters.Replace(string(data)))
+		obj := reflect.New(reflect.TypeOf(template).Elem()).Interface().(client.Object)
+		if err := json.Unmarshal(data, obj); err != nil {
+			return nil, fmt.Errorf("failed to render %s of tier %s: %w", objectKey(template), t.Name, err)
+		}
+		obj.SetNamespace(namespace)
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[TierLabel] = t.Name
+		obj.SetLabels(labels)
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// Escape the value for a JSON string, without the enclosing quotes
+func jsonEscape(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted[1 : len(quoted)-1])
+}
+
+// Apply creates or updates the objects of the tier in the namespace owned by
+// the user with the given email, deletes the objects left from its previous
+// tier and labels the namespace with the tier
+func (t *Tier) Apply(ctx context.Context, cl client.Client, namespace string, owner string) error {
+	objects, err := t.Objects(namespace, owner)
+	if err != nil {
+		return err
+	}
+	applied := map[string]bool{}
+	for _, obj := range objects {
+		if err := apply(ctx, cl, obj); err != nil {
+			return fmt.Errorf("failed to apply %s of tier %s: %w", objectKey(obj), t.Name, err)
+		}
+		applied[objectKey(obj)] = true
+	}
+
+	for _, list := range templateLists() {
+		err := cl.List(ctx, list, client.InNamespace(namespace), client.HasLabels{TierLabel})
+		if err != nil {
+			return err
+		}
+		previous, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range previous {
+			obj := item.(client.Object)
+			if applied[objectKey(obj)] {
+				continue
+			}
+			if err := cl.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	// the namespace is labelled last so that the tier is applied again if
+	// an object failed to
+	ns := &core.Namespace{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return err
+	}
+	if ns.Labels[TierLabel] == t.Name {
+		return nil
+	}
+	patch := client.MergeFrom(ns.DeepCopy())
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	ns.Labels[TierLabel] = t.Name
+	return cl.Patch(ctx, ns, patch)
+}
+
+// Create the object, or update it if it exists
+func apply(ctx context.Context, cl client.Client, obj client.Object) error {
+	existing := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+	err := cl.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if apierrors.IsNotFound(err) {
+		return cl.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return cl.Update(ctx, obj)
+}
@@ -0,0 +1,163 @@
+package tiers_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/konflux-ci/workspace-manager/pkg/tiers"
+	core "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTiers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Namespace tiers Suite")
+}
+
+const config = `
+default: base
+tiers:
+- name: base
+  resourceQuotas:
+  - metadata: {name: compute}
+    spec:
+      hard: {requests.cpu: "2"}
+  serviceAccounts:
+  - metadata: {name: pipeline}
+  roleBindings:
+  - metadata: {name: owner-edit}
+    subjects: [{kind: User, apiGroup: rbac.authorization.k8s.io, name: "${OWNER}"}]
+    roleRef: {kind: ClusterRole, apiGroup: rbac.authorization.k8s.io, name: edit}
+- name: large
+  resourceQuotas:
+  - metadata: {name: compute}
+    spec:
+      hard: {requests.cpu: "8"}
+  limitRanges:
+  - metadata: {name: defaults, annotations: {konflux.ci/namespace: "${NAMESPACE}"}}
+`
+
+var _ = Describe("Parse", func() {
+	It("reads the tiers", func() {
+		catalog, err := tiers.Parse([]byte(config))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.Names()).To(Equal([]string{"base", "large"}))
+		Expect(catalog.DefaultTier().Name).To(Equal("base"))
+	})
+
+	It("defaults to the first tier", func() {
+		catalog, err := tiers.Parse([]byte("tiers: [{name: small}, {name: large}]"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.DefaultTier().Name).To(Equal("small"))
+	})
+
+	DescribeTable("rejecting invalid tiers",
+		func(data string, expected string) {
+			_, err := tiers.Parse([]byte(data))
+			Expect(err).To(MatchError(ContainSubstring(expected)))
+		},
+		Entry("without tiers", `tiers: []`, "no tier given"),
+		Entry("with an unknown field", `tiers: [{name: base, deployments: []}]`, "unknown field"),
+		Entry("without name", `tiers: [{description: nameless}]`, "a tier has no name"),
+		Entry("defined twice", `tiers: [{name: base}, {name: base}]`, "tier base is defined twice"),
+		Entry("with an unknown default", `{default: gold, tiers: [{name: base}]}`, "the default tier gold is not defined"),
+		Entry("with an object without name", `tiers: [{name: base, serviceAccounts: [{}]}]`,
+			"a ServiceAccount has no name",
+		),
+		Entry("with an object setting its namespace",
+			`tiers: [{name: base, serviceAccounts: [{metadata: {name: a, namespace: b}}]}]`,
+			"ServiceAccount/a can't set its namespace",
+		),
+		Entry("with an object defined twice",
+			`tiers: [{name: base, serviceAccounts: [{metadata: {name: a}}, {metadata: {name: a}}]}]`,
+			"ServiceAccount/a is defined twice",
+		),
+	)
+})
+
+var _ = Describe("Tier", func() {
+	var (
+		catalog *tiers.Catalog
+		cl      client.Client
+	)
+
+	BeforeEach(func() {
+		var err error
+		catalog, err = tiers.Parse([]byte(config))
+		Expect(err).NotTo(HaveOccurred())
+		cl = fake.NewClientBuilder().WithObjects(
+			&core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}},
+		).Build()
+	})
+
+	It("renders the templates for a namespace", func() {
+		objects, err := catalog.DefaultTier().Objects("tenant", "user@konflux.dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(HaveLen(3))
+		for _, obj := range objects {
+			Expect(obj.GetNamespace()).To(Equal("tenant"))
+			Expect(obj.GetLabels()).To(HaveKeyWithValue(tiers.TierLabel, "base"))
+		}
+		binding := objects[2].(*rbacv1.RoleBinding)
+		Expect(binding.Subjects[0].Name).To(Equal("user@konflux.dev"))
+	})
+
+	It("escapes the parameters", func() {
+		owner := `a@b.c"},{"kind":"Group","apiGroup":"rbac.authorization.k8s.io","name":"system:authenticated`
+		objects, err := catalog.DefaultTier().Objects("tenant", owner)
+		Expect(err).NotTo(HaveOccurred())
+		binding := objects[2].(*rbacv1.RoleBinding)
+		Expect(binding.Subjects).To(HaveLen(1))
+		Expect(binding.Subjects[0].Name).To(Equal(owner))
+	})
+
+	It("applies the templates and labels the namespace", func() {
+		Expect(catalog.DefaultTier().Apply(context.Background(), cl, "tenant", "user@konflux.dev")).To(Succeed())
+
+		quota := &core.ResourceQuota{}
+		Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: "tenant", Name: "compute"}, quota)).
+			To(Succeed())
+		cpu := quota.Spec.Hard[core.ResourceRequestsCPU]
+		Expect(cpu.String()).To(Equal("2"))
+		ns := &core.Namespace{}
+		Expect(cl.Get(context.Background(), client.ObjectKey{Name: "tenant"}, ns)).To(Succeed())
+		Expect(ns.Labels).To(HaveKeyWithValue(tiers.TierLabel, "base"))
+	})
+
+	It("replaces the objects of the previous tier", func() {
+		Expect(catalog.DefaultTier().Apply(context.Background(), cl, "tenant", "user@konflux.dev")).To(Succeed())
+		unmanaged := &core.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "tenant"}}
+		Expect(cl.Create(context.Background(), unmanaged)).To(Succeed())
+		large, found := catalog.Get("large")
+		Expect(found).To(BeTrue())
+		Expect(large.Apply(context.Background(), cl, "tenant", "user@konflux.dev")).To(Succeed())
+
+		quota := &core.ResourceQuota{}
+		Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: "tenant", Name: "compute"}, quota)).
+			To(Succeed())
+		Expect(quota.Labels).To(HaveKeyWithValue(tiers.TierLabel, "large"))
+		cpu := quota.Spec.Hard[core.ResourceRequestsCPU]
+		Expect(cpu.String()).To(Equal("8"))
+		limits := &core.LimitRange{}
+		Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: "tenant", Name: "defaults"}, limits)).
+			To(Succeed())
+		Expect(limits.Annotations).To(HaveKeyWithValue("konflux.ci/namespace", "tenant"))
+
+		account := &core.ServiceAccount{}
+		err := cl.Get(context.Background(), client.ObjectKey{Namespace: "tenant", Name: "pipeline"}, account)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		// the objects created by other means are kept
+		Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: "tenant", Name: "builder"}, account)).
+			To(Succeed())
+		ns := &core.Namespace{}
+		Expect(cl.Get(context.Background(), client.ObjectKey{Name: "tenant"}, ns)).To(Succeed())
+		Expect(ns.Labels).To(HaveKeyWithValue(tiers.TierLabel, "large"))
+	})
+})
@@ -0,0 +1,112 @@
+package username
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// Suffix appended to the compliant username to build the name of the user namespace
+	namespaceSuffix = "-tenant"
+	// Maximum length of a compliant username, leaving room for the
+	// namespace suffix and for the collision suffixes
+	maxLength = validation.DNS1123LabelMaxLength - len(namespaceSuffix) - 4
+	// Number of collision suffixes tried before giving up
+	maxAttempts = 100
+)
+
+// Names, or name prefixes when ending with *, users can't get by default
+var DefaultReserved = []string{"kube-*", "openshift-*", "default"}
+
+var (
+	invalidChars     = regexp.MustCompile("[^a-z0-9-]+")
+	repeatedHyphens  = regexp.MustCompile("-{2,}")
+	reservedFallback = "user"
+)
+
+// Namespace returns the name of the default namespace of a user
+func Namespace(compliantUsername string) string {
+	return compliantUsername + namespaceSuffix
+}
+
+// IsNamespace reports whether name is the name of the default namespace of a
+// user
+func IsNamespace(name string) bool {
+	return strings.HasSuffix(name, namespaceSuffix)
+}
+
+// Generator derives DNS-1123 compliant usernames from emails
+type Generator struct {
+	reserved []string
+}
+
+// NewGenerator creates a Generator refusing DefaultReserved and the reserved
+// names. A name ending with * reserves all the names starting with the same
+// prefix.
+func NewGenerator(reserved []string) *Generator {
+	all := append([]string{}, DefaultReserved...)
+	return &Generator{reserved: append(all, reserved...)}
+}
+
+// Reserved reports whether the name is on the blocklist
+func (g *Generator) Reserved(name string) bool {
+	for _, r := range g.reserved {
+		if prefix, found := strings.CutSuffix(r, "*"); found {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Compliant transforms an email into a DNS-1123 label that is not reserved,
+// without checking for collisions
+func (g *Generator) Compliant(email string) string {
+	name, _, _ := strings.Cut(strings.ToLower(email), "@")
+	name = invalidChars.ReplaceAllString(name, "-")
+	name = repeatedHyphens.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+	if len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "-")
+	}
+	if name == "" {
+		name = reservedFallback
+	}
+	if g.Reserved(name) || g.Reserved(Namespace(name)) {
+		name = strings.TrimRight(reservedFallback+"-"+name, "-")
+		if len(name) > maxLength {
+			name = strings.TrimRight(name[:maxLength], "-")
+		}
+	}
+	return name
+}
+
+// Generate returns a compliant username for the email that is not taken.
+// When the compliant username is taken, numbered suffixes are tried until
+// a free one is found.
+func (g *Generator) Generate(
+	ctx context.Context,
+	email string,
+	taken func(ctx context.Context, name string) (bool, error),
+) (string, error) {
+	base := g.Compliant(email)
+	name := base
+	for attempt := 2; attempt <= maxAttempts+1; attempt++ {
+		isTaken, err := taken(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if !isTaken {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s-%d", base, attempt)
+	}
+	return "", fmt.Errorf("no free username found for %s", email)
+}
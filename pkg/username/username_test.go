@@ -0,0 +1,83 @@
+package username_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/konflux-ci/workspace-manager/pkg/username"
+)
+
+func TestUsername(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Username Suite")
+}
+
+var _ = DescribeTable("Deriving a compliant username",
+	func(email string, expected string) {
+		name := username.NewGenerator(nil).Compliant(email)
+		Expect(name).To(Equal(expected))
+		Expect(validation.IsDNS1123Label(username.Namespace(name))).To(BeEmpty())
+	},
+	Entry("keeps the local part of the email", "user@konflux.dev", "user"),
+	Entry("lowercases", "John.Doe@konflux.dev", "john-doe"),
+	Entry("replaces invalid characters", "john_doe+test@konflux.dev", "john-doe-test"),
+	Entry("collapses hyphens", "john..--doe@konflux.dev", "john-doe"),
+	Entry("trims hyphens", "-john-@konflux.dev", "john"),
+	Entry("handles an empty local part", "@konflux.dev", "user"),
+	Entry("handles non ascii characters", "jöhn@konflux.dev", "j-hn"),
+	Entry("truncates long names", strings.Repeat("a", 80)+"@konflux.dev", strings.Repeat("a", 52)),
+	Entry("prefixes the reserved prefixes", "kube-system@konflux.dev", "user-kube-system"),
+	Entry("prefixes the reserved names", "default@konflux.dev", "user-default"),
+	Entry("prefixes openshift names", "openshift-user@konflux.dev", "user-openshift-user"),
+)
+
+var _ = Describe("User namespaces", func() {
+	It("recognizes the names of the user namespaces", func() {
+		Expect(username.IsNamespace(username.Namespace("jdoe"))).To(BeTrue())
+		Expect(username.IsNamespace("jdoe")).To(BeFalse())
+	})
+})
+
+var _ = Describe("Reserved usernames", func() {
+	It("adds the configured blocklist to the default one", func() {
+		g := username.NewGenerator([]string{"admin", "sys-*"})
+		Expect(g.Reserved("admin")).To(BeTrue())
+		Expect(g.Reserved("sys-user")).To(BeTrue())
+		Expect(g.Reserved("kube-system")).To(BeTrue(), "the default reserved names should be kept")
+		Expect(g.Compliant("admin@konflux.dev")).To(Equal("user-admin"))
+	})
+})
+
+var _ = Describe("Generating a username", func() {
+	g := username.NewGenerator(nil)
+
+	It("suffixes the username until it is free", func() {
+		taken := map[string]bool{"user": true, "user-2": true}
+		name, err := g.Generate(context.Background(), "user@konflux.dev", func(_ context.Context, name string) (bool, error) {
+			return taken[name], nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("user-3"))
+	})
+
+	It("fails when no username is free", func() {
+		_, err := g.Generate(context.Background(), "user@konflux.dev", func(context.Context, string) (bool, error) {
+			return true, nil
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("passes the lookup errors", func() {
+		lookupErr := errors.New("lookup failed")
+		_, err := g.Generate(context.Background(), "user@konflux.dev", func(context.Context, string) (bool, error) {
+			return false, lookupErr
+		})
+		Expect(err).To(MatchError(lookupErr))
+	})
+})